@@ -39,6 +39,20 @@ func (o *TeflonObject) Get(exs string) (res interface{}, err error) {
 	return res, nil
 }
 
+// Find() evaluates an object selector expression and returns the matching
+// objects.
+func (o *TeflonObject) Find(exs string) (oSl []*TeflonObject, err error) {
+	log.Printf("DEBUG: Inside Find(): o.Path: %v  ex: %v", o.Path, exs)
+
+	ex, err := NewExpr(exs)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Context{Dir: o}
+	return ex.Objects(c)
+}
+
 // CreateShow() creates new Teflon show.
 func (o *TeflonObject) CreateShow(exs string, protoName string) (oSl []*TeflonObject, err error) {
 	log.Printf("DEBUG: Inside CreateShow(): o.Path: %v  exs: %v", o.Path, exs)
@@ -71,6 +85,7 @@ func (o *TeflonObject) CreateShow(exs string, protoName string) (oSl []*TeflonOb
 		if err != nil {
 			log.Fatalln("ABORT: Couldn't copy show proto:", err)
 		}
+		forget(fsp)
 		log.Printf("SUCCESS: Created new show: %s (%s)", fsp, protoName)
 
 		o, err := NewTeflonObject(fsp)
@@ -79,6 +94,7 @@ func (o *TeflonObject) CreateShow(exs string, protoName string) (oSl []*TeflonOb
 		}
 
 		o.ShowRoot = true
+		o.ShowProto = protoName
 
 		if o.SyncMeta() != nil {
 			log.Fatalln("ABORT: Couldn't write meta of newly created show:", err)
@@ -106,45 +122,80 @@ func (o *TeflonObject) CreateObject(exs string, file bool) (oSl []*TeflonObject,
 	}
 
 	for _, fsp := range res {
-		if _, err := os.Stat(fsp); !os.IsNotExist(err) {
-			log.Println("WARNING: Target already exists:", fsp)
-			continue
+		no, err := o.createPath(fsp, file)
+		if err != nil {
+			return nil, err
+		}
+		if no != nil {
+			oSl = append(oSl, no)
 		}
+	}
+	return oSl, nil
+}
 
-		rch := make(chan *TeflonObject)
-		Events <- Event{o, PreNew, rch}
-		<-rch
+// Creates a new FS object at a file-system path inside the object, taken
+// literally, and triggers a new event. Existing targets and failures to create
+// them are only logged and result in a nil object.
+func (o *TeflonObject) createPath(fsp string, file bool) (*TeflonObject, error) {
+	if _, err := os.Stat(fsp); !os.IsNotExist(err) {
+		log.Println("WARNING: Target already exists:", fsp)
+		return nil, nil
+	}
 
-		if file {
-			f, err := os.Create(fsp)
-			if err != nil {
-				log.Println("WARNING: Couldn't create file:", fsp, err)
-				continue
-			}
-			f.Close()
-		} else {
-			err := os.Mkdir(fsp, 0755)
-			if err != nil {
-				log.Println("WARNING: Couldn't create directory:", fsp, err)
-				continue
-			}
-		}
+	rch := make(chan *EventResult)
+	Events <- Event{o, PreNew, rch}
+	<-rch
 
-		o, err := NewTeflonObject(fsp)
+	if file {
+		f, err := os.Create(fsp)
 		if err != nil {
-			return nil, err
+			log.Println("WARNING: Couldn't create file:", fsp, err)
+			return nil, nil
 		}
+		f.Close()
+	} else {
+		err := os.Mkdir(fsp, 0755)
+		if err != nil {
+			log.Println("WARNING: Couldn't create directory:", fsp, err)
+			return nil, nil
+		}
+	}
+	forget(fsp)
 
-		oSl = append(oSl, o)
-
-		Events <- Event{o, PostNew, nil}
+	no, err := NewTeflonObject(fsp)
+	if err != nil {
+		return nil, err
+	}
 
-		log.Println("SUCCESS: Created:", fsp)
+	Events <- Event{no, PostNew, rch}
+	res := <-rch
+	for _, c := range res.Contracts {
+		log.Println("SUCCESS: Executed contract:", c.Path)
 	}
-	return oSl, nil
+	if res.Err != nil {
+		log.Println("WARNING: Couldn't execute contracts:", fsp, res.Err)
+	}
+
+	log.Println("SUCCESS: Created:", fsp)
+	return no, nil
 }
 
 func (o *TeflonObject) SetContractPattern(exs string, pat string) (oSl []*TeflonObject, err error) {
+	return o.updateContracts(exs, func(c *meta.Contract) {
+		c.Pattern = pat
+	})
+}
+
+// SetContractDirs() sets the directories the contracts create in the new
+// objects they apply to.
+func (o *TeflonObject) SetContractDirs(exs string, dirs []string) (oSl []*TeflonObject, err error) {
+	return o.updateContracts(exs, func(c *meta.Contract) {
+		c.Dirs = dirs
+	})
+}
+
+// Updates the contract of the objects generated by the expression.
+func (o *TeflonObject) updateContracts(exs string, fn func(*meta.Contract)) (oSl []*TeflonObject, err error) {
 	ex, err := NewExpr(exs)
 	if err != nil {
 		return nil, err
@@ -166,8 +217,13 @@ func (o *TeflonObject) SetContractPattern(exs string, pat string) (oSl []*Teflon
 		if err != nil {
 			return nil, err
 		}
-		o.Contract = &meta.Contract{Pattern: pat}
-		err = o.SyncMeta()
+		err = o.UpdateMeta(func(o *TeflonObject) error {
+			if o.Contract == nil {
+				o.Contract = &meta.Contract{}
+			}
+			fn(o.Contract)
+			return nil
+		})
 		if err != nil {
 			return oSl, err
 		}
@@ -0,0 +1,241 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ALE is a parsed Avid Log Exchange file.
+type ALE struct {
+	Heading map[string]string
+	Columns []string
+	Rows    [][]string
+}
+
+// ALEConfig is the part of the show config that controls ALE imports.
+type ALEConfig struct {
+	// Columns maps ALE column names to user metadata keys. Only the listed
+	// columns are imported. If empty, all columns are imported with their
+	// names converted to keys, like 'Source File' to 'sourceFile'.
+	Columns map[string]string `json:",omitempty"`
+
+	// Match lists the rules for finding the objects of an ALE row. The rules
+	// are tried in order, the first that finds any object wins. If empty,
+	// DefaultALEMatch is used.
+	Match []ALEMatch `json:",omitempty"`
+}
+
+// ALEMatch is a rule that matches the value of an ALE column to the value of a
+// user metadata key of objects. An empty Key matches the name of the object
+// without its extension, or the base name of sequences.
+type ALEMatch struct {
+	Column string
+	Key    string `json:",omitempty"`
+}
+
+// DefaultALEMatch matches rows by source file name, then by clip name and then
+// by tape name.
+var DefaultALEMatch = []ALEMatch{
+	{Column: "Source File"},
+	{Column: "Name", Key: "clip"},
+	{Column: "Name"},
+	{Column: "Tape", Key: "reel"},
+}
+
+// ParseALE() parses an ALE file. Only tab delimited files are supported.
+func ParseALE(r io.Reader) (*ALE, error) {
+	a := &ALE{Heading: map[string]string{}}
+	section := ""
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		l := strings.TrimRight(sc.Text(), "\r")
+		switch strings.TrimSpace(l) {
+		case "":
+			continue
+		case "Heading", "Column", "Data":
+			section = strings.TrimSpace(l)
+			continue
+		}
+
+		fs := strings.Split(l, "\t")
+		switch section {
+		case "Heading":
+			if len(fs) > 1 {
+				a.Heading[fs[0]] = fs[1]
+			}
+		case "Column":
+			if a.Columns != nil {
+				return nil, errors.New("More than one column line.")
+			}
+			a.Columns = fs
+		case "Data":
+			if a.Columns == nil {
+				return nil, errors.New("Data before the column line.")
+			}
+			for len(fs) < len(a.Columns) {
+				fs = append(fs, "")
+			}
+			a.Rows = append(a.Rows, fs[:len(a.Columns)])
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if d, ok := a.Heading["FIELD_DELIM"]; ok && d != "TABS" {
+		return nil, errors.New("Unsupported field delimiter: " + d)
+	}
+	if a.Columns == nil {
+		return nil, errors.New("Missing column line.")
+	}
+	return a, nil
+}
+
+// Converts an ALE column name to a metadata key.
+func aleKey(col string) string {
+	words := strings.FieldsFunc(col, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	k := ""
+	for i, w := range words {
+		w = strings.ToLower(w)
+		if i > 0 {
+			w = strings.Title(w)
+		}
+		k += w
+	}
+	return k
+}
+
+// Returns the name of an object without extension or frame pattern.
+func plainName(o *TeflonObject) string {
+	if o.IsSeq() {
+		return o.Seq.BaseName
+	}
+	n := o.FileInfo.Name
+	if o.FileInfo.IsDir {
+		return n
+	}
+	return strings.TrimSuffix(n, filepath.Ext(n))
+}
+
+// PlanALE() matches the rows of an ALE to the objects under the object using
+// the show's ALE config, and computes the changes of their user metadata
+// without writing anything. A row can match several objects, like the plates
+// made from the same clip. Values are compared without extension. Rows that
+// don't match any object are skipped with a warning. The plans can be carried
+// out by ApplyImport().
+func (o *TeflonObject) PlanALE(a *ALE) ([]ImportPlan, error) {
+	c, err := o.Config()
+	if err != nil {
+		return nil, err
+	}
+	ac := c.ALE
+	if ac == nil {
+		ac = &ALEConfig{}
+	}
+	rules := ac.Match
+	if len(rules) == 0 {
+		rules = DefaultALEMatch
+	}
+
+	keys := map[int]string{}
+	for i, col := range a.Columns {
+		if len(ac.Columns) == 0 {
+			keys[i] = aleKey(col)
+		} else if k, ok := ac.Columns[col]; ok {
+			keys[i] = k
+		}
+	}
+
+	objs := []*TeflonObject{}
+	err = o.Walk(func(d *TeflonObject) error {
+		if d != o {
+			objs = append(objs, d)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	colIndex := map[string]int{}
+	for i, col := range a.Columns {
+		colIndex[col] = i
+	}
+	plain := func(s string) string {
+		return strings.TrimSuffix(s, filepath.Ext(s))
+	}
+
+	plans := []ImportPlan{}
+	for ri, row := range a.Rows {
+		var matched []*TeflonObject
+		for _, r := range rules {
+			ci, ok := colIndex[r.Column]
+			if !ok || row[ci] == "" {
+				continue
+			}
+			want := plain(filepath.Base(row[ci]))
+			for _, d := range objs {
+				have := plainName(d)
+				if r.Key != "" {
+					have = plain(d.UserData[r.Key])
+				}
+				if have == want {
+					matched = append(matched, d)
+				}
+			}
+			if len(matched) > 0 {
+				break
+			}
+		}
+		if len(matched) == 0 {
+			log.Printf("WARNING: Couldn't match ALE row %d, skipping.", ri+1)
+			continue
+		}
+
+		for _, d := range matched {
+			p := ImportPlan{Path: d.Path}
+			for ci, k := range keys {
+				v := row[ci]
+				if v == "" || k == "" {
+					continue
+				}
+				ov, ok := d.UserData[k]
+				if ok && ov == v {
+					continue
+				}
+				ch := MetaChange{Key: k, New: &row[ci]}
+				if ok {
+					old := ov
+					ch.Old = &old
+				}
+				p.Changes = append(p.Changes, ch)
+			}
+			if len(p.Changes) > 0 {
+				sort.Slice(p.Changes, func(i, j int) bool { return p.Changes[i].Key < p.Changes[j].Key })
+				plans = append(plans, p)
+			}
+		}
+	}
+	return plans, nil
+}
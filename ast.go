@@ -142,6 +142,19 @@ func (M *MetaNode) Eval(c *Context) (interface{}, error) {
 	return val, nil
 }
 
+// Converts an operand of an arithmetic node to a number. User metadata values
+// are strings, so numeric strings are accepted too.
+func number(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
 func (a *AddNode) Eval(c *Context) (interface{}, error) {
 	fi, err := a.first.Eval(c)
 	if err != nil {
@@ -155,11 +168,14 @@ func (a *AddNode) Eval(c *Context) (interface{}, error) {
 
 	var v interface{}
 
-	switch f := fi.(type) {
-	case float64:
-		switch s := si.(type) {
-		case float64:
-			v = f + s
+	f, fok := number(fi)
+	s, sok := number(si)
+	if fok && sok {
+		v = f + s
+	} else if fs, ok := fi.(string); ok {
+		// Strings are concatenated, for names built from other keys.
+		if ss, ok := si.(string); ok {
+			v = fs + ss
 		}
 	}
 	return v, nil
@@ -178,10 +194,8 @@ func (a *SubNode) Eval(c *Context) (interface{}, error) {
 
 	var v interface{}
 
-	switch f := fi.(type) {
-	case float64:
-		switch s := si.(type) {
-		case float64:
+	if f, ok := number(fi); ok {
+		if s, ok := number(si); ok {
 			v = f - s
 		}
 	}
@@ -201,10 +215,8 @@ func (a *MulNode) Eval(c *Context) (interface{}, error) {
 
 	var v interface{}
 
-	switch f := fi.(type) {
-	case float64:
-		switch s := si.(type) {
-		case float64:
+	if f, ok := number(fi); ok {
+		if s, ok := number(si); ok {
 			v = f * s
 		}
 	}
@@ -224,10 +236,8 @@ func (a *DivNode) Eval(c *Context) (interface{}, error) {
 
 	var v interface{}
 
-	switch f := fi.(type) {
-	case float64:
-		switch s := si.(type) {
-		case float64:
+	if f, ok := number(fi); ok {
+		if s, ok := number(si); ok {
 			v = f / s
 		}
 	}
@@ -0,0 +1,187 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gradient-images/teflon/internal/meta"
+
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+)
+
+// ErrNotFile is returned when a file operation is called on a directory or a
+// sequence.
+var ErrNotFile = errors.New("Not a file.")
+
+// Results of checksum verification.
+const (
+	VerifyOK       = "ok"
+	VerifyMismatch = "mismatch"
+	VerifyMissing  = "missing"
+	VerifyNew      = "new"
+)
+
+// VerifyResult is the verification result of a single file.
+type VerifyResult struct {
+	Path   string
+	Status string
+}
+
+func (r VerifyResult) String() string {
+	return fmt.Sprintf("%-8s %s", r.Status, r.Path)
+}
+
+// HashFile() returns the SHA-256 and the xxHash64 checksum of a file in
+// hexadecimal. The file is read only once for both.
+func HashFile(fspath string) (sha, xxh string, err error) {
+	f, err := os.Open(fspath)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	sh := sha256.New()
+	xh := newXXH64()
+	if _, err := io.Copy(io.MultiWriter(sh, xh), f); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(sh.Sum(nil)), hex.EncodeToString(xh.Sum(nil)), nil
+}
+
+// Tells if the stored checksum was computed from the current state of the
+// file, judged by its size and modification time.
+func (o *TeflonObject) checksumCurrent() bool {
+	c := o.Checksum
+	return c != nil && c.Size == o.FileInfo.Size && protobuf.Equal(c.ModTime, o.FileInfo.ModTime)
+}
+
+// UpdateChecksum() computes the checksums of the object's file and stores them
+// in its metadata. Files whose size and modification time didn't change since
+// they were last hashed are skipped, unless force is set. It tells if the file
+// was hashed.
+func (o *TeflonObject) UpdateChecksum(force bool) (bool, error) {
+	if o.FileInfo.IsDir || o.IsSeq() {
+		return false, ErrNotFile
+	}
+	if !force && o.checksumCurrent() {
+		return false, nil
+	}
+	sha, xxh, err := HashFile(o.Path)
+	if err != nil {
+		return false, err
+	}
+	c := &meta.Checksum{
+		SHA256:  sha,
+		XXH64:   xxh,
+		Size:    o.FileInfo.Size,
+		ModTime: o.FileInfo.ModTime,
+		Time:    ptypes.TimestampNow(),
+	}
+	return true, o.UpdateMeta(func(o *TeflonObject) error {
+		o.Checksum = c
+		return nil
+	})
+}
+
+// VerifyChecksum() re-hashes the object's file and compares the result with the
+// stored checksum. Files without stored checksum are reported as new.
+func (o *TeflonObject) VerifyChecksum() (string, error) {
+	if o.FileInfo.IsDir || o.IsSeq() {
+		return "", ErrNotFile
+	}
+	if o.Checksum == nil {
+		return VerifyNew, nil
+	}
+	sha, xxh, err := HashFile(o.Path)
+	if err != nil {
+		return "", err
+	}
+	c := o.Checksum
+	if (c.SHA256 != "" && c.SHA256 != sha) || (c.XXH64 != "" && c.XXH64 != xxh) {
+		return VerifyMismatch, nil
+	}
+	return VerifyOK, nil
+}
+
+// Verify() verifies the checksums of the files among the object and its
+// descendants. Besides the results of the existing files, the files which have
+// a checksum in a meta file but don't exist anymore are reported as missing.
+// The results are sorted by path.
+func (o *TeflonObject) Verify() ([]VerifyResult, error) {
+	res := []VerifyResult{}
+	err := o.WalkFiles(func(f *TeflonObject) error {
+		st, err := f.VerifyChecksum()
+		if err != nil {
+			return err
+		}
+		res = append(res, VerifyResult{Path: f.Path, Status: st})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if o.FileInfo.IsDir {
+		missing, err := missingFiles(o.Path)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range missing {
+			res = append(res, VerifyResult{Path: m, Status: VerifyMissing})
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Path < res[j].Path })
+	return res, nil
+}
+
+// Finds the files under a directory that have a checksum in their meta file,
+// but don't exist.
+func missingFiles(root string) ([]string, error) {
+	missing := []string{}
+	err := filepath.Walk(root, func(fsp string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() || fi.Name() != teflonDirName {
+			return nil
+		}
+		ms, err := filepath.Glob(filepath.Join(fsp, "*"+metaExtension))
+		if err != nil {
+			return err
+		}
+		for _, m := range ms {
+			target := filepath.Join(filepath.Dir(fsp), strings.TrimSuffix(filepath.Base(m), metaExtension))
+			if Exist(target) || seqExist(target) {
+				continue
+			}
+			pm := &meta.PersistentMeta{}
+			if err := readMetaFile(m, pm); err == nil && pm.Checksum != nil {
+				missing = append(missing, target)
+			}
+		}
+		return filepath.SkipDir
+	})
+	return missing, err
+}
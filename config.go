@@ -0,0 +1,119 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+)
+
+const configFileName = "config.json"
+
+// ShowConfig holds the settings of a show. It is read from '.teflon/config.json'
+// in the show root. Since show prototypes are copied to create new shows, a
+// config file in a show prototype becomes the default config of its shows.
+type ShowConfig struct {
+	// MetaFormat is the format meta files are written in. See the Format*
+	// constants. Empty means FormatProtobuf.
+	MetaFormat string `json:",omitempty"`
+
+	// MetaStore is where the metadata of the objects of the show is kept. See
+	// the Store* constants. Empty means StoreSidecar. The show root itself
+	// always uses sidecar files.
+	MetaStore string `json:",omitempty"`
+
+	// Index turns on the index file of the show, which caches the directories
+	// and the metadata of the show for queries. See 'teflon index'. It only
+	// takes effect with sidecar files.
+	Index bool `json:",omitempty"`
+
+	// ALE configures how 'teflon import-ale' maps ALE columns to metadata.
+	ALE *ALEConfig `json:",omitempty"`
+
+	// Derived maps keys to meta selector expressions, like 'cutOut-cutIn+1'.
+	// Derived fields are not stored, they are evaluated when the metadata of an
+	// object is queried, and can't be set.
+	Derived map[string]string `json:",omitempty"`
+
+	// Namespaces controls which namespaces of user metadata keys the command
+	// line can write.
+	Namespaces *NamespaceConfig `json:",omitempty"`
+
+	// The parsed expressions of the derived fields.
+	derived map[string]*Expr
+}
+
+// configs associates loaded show configs to show root paths.
+var configs = map[string]*ShowConfig{}
+
+// Config() returns the config of the show the object belongs to. Objects
+// outside of shows and shows without a config file get the default config.
+func (o *TeflonObject) Config() (*ShowConfig, error) {
+	if o.Show == nil {
+		return &ShowConfig{}, nil
+	}
+	if c, ok := configs[o.Show.Path]; ok {
+		return c, nil
+	}
+
+	c := &ShowConfig{}
+	cf := o.Show.ConfigFile()
+	if Exist(cf) {
+		in, err := ioutil.ReadFile(cf)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(in, c); err != nil {
+			return nil, fmt.Errorf("Couldn't parse show config %s: %v", cf, err)
+		}
+		if _, ok := metaStores[c.MetaStore]; !ok {
+			return nil, fmt.Errorf("Unknown meta store in show config %s: %s", cf, c.MetaStore)
+		}
+		if _, err := c.derivedExprs(); err != nil {
+			return nil, fmt.Errorf("Invalid show config %s: %v", cf, err)
+		}
+	}
+	configs[o.Show.Path] = c
+	return c, nil
+}
+
+// ConfigFile() returns the path of the config file of a show root.
+func (o *TeflonObject) ConfigFile() string {
+	return filepath.Join(o.Path, teflonDirName, configFileName)
+}
+
+// SaveConfig() writes the config of a show root to disk.
+func (o *TeflonObject) SaveConfig(c *ShowConfig) error {
+	out, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := o.createTeflonDir(); err != nil {
+		return err
+	}
+	l, err := LockFile(o.ConfigFile())
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	if err := writeFileAtomic(o.ConfigFile(), append(out, '\n'), 0644); err != nil {
+		return err
+	}
+	c.derived = nil
+	configs[o.Path] = c
+	return nil
+}
@@ -0,0 +1,144 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Contracts are objects in the '.teflon/contract' directory of an object. A
+// contract applies to the new objects under the object that are selected by
+// its Pattern, relative to the object. When a contract is executed, the content
+// of the contract object is copied into the new object as a template, the user
+// metadata of the contract object is set on the new object, and the
+// directories listed in the contract are created.
+
+// Contracts() returns the contracts that apply to the object, the ones of the
+// nearest ancestor first.
+func (o *TeflonObject) Contracts() ([]*TeflonObject, error) {
+	cs := []*TeflonObject{}
+	for p := o.Parent; p != nil; p = p.Parent {
+		cfs := filepath.Join(p.Path, contractDirName)
+		if IsDir(cfs) {
+			cd, err := NewTeflonObject(cfs)
+			if err != nil {
+				return nil, err
+			}
+			for _, n := range cd.ChildrenNames() {
+				if strings.HasPrefix(n, ".") {
+					continue
+				}
+				c, err := NewTeflonObject(filepath.Join(cfs, n))
+				if err != nil {
+					return nil, err
+				}
+				if c.Contract.GetPattern() == "" {
+					continue
+				}
+				ok, err := p.selects(c.Contract.Pattern, o)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					cs = append(cs, c)
+				}
+			}
+		}
+		if p.ShowRoot {
+			break
+		}
+	}
+	return cs, nil
+}
+
+// Tells if the object selector expression selects the target from the object.
+func (o *TeflonObject) selects(exs string, target *TeflonObject) (bool, error) {
+	objs, err := o.Find(exs)
+	if err != nil {
+		return false, err
+	}
+	for _, m := range objs {
+		if m.Path == target.Path {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ExecContract() executes a contract on the object. Existing content and
+// metadata of the object are kept, so contracts of nearer ancestors, which are
+// executed first, win.
+func (o *TeflonObject) ExecContract(c *TeflonObject) error {
+	if o.FileInfo.IsDir {
+		if c.FileInfo.IsDir {
+			if err := copyTemplate(c.Path, o.Path); err != nil {
+				return err
+			}
+		}
+		for _, d := range c.Contract.Dirs {
+			if err := os.MkdirAll(filepath.Join(o.Path, d), 0755); err != nil {
+				return err
+			}
+		}
+		forget(o.Path)
+	} else if !c.FileInfo.IsDir && o.FileInfo.Size == 0 {
+		in, err := ioutil.ReadFile(c.Path)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(o.Path, in, 0644); err != nil {
+			return err
+		}
+	}
+
+	if len(c.UserData) == 0 {
+		return nil
+	}
+	return o.UpdateMeta(func(o *TeflonObject) error {
+		for k, v := range c.UserData {
+			if _, ok := o.UserData[k]; !ok {
+				o.SetMeta(k, v)
+			}
+		}
+		return nil
+	})
+}
+
+// Copies the content of a template directory into a directory. Teflon
+// directories are skipped and existing files are kept.
+func copyTemplate(src, dst string) error {
+	return filepath.Walk(src, func(fspath string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() && fi.Name() == teflonDirName {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(src, fspath)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if fi.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if Exist(target) {
+			return nil
+		}
+		return copyFile(fspath, target)
+	})
+}
@@ -32,6 +32,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/gradient-images/teflon/internal/meta"
@@ -67,23 +68,43 @@ type TeflonObject struct {
 	FileInfo meta.FileInfo
 	Parent   *TeflonObject
 	meta.PersistentMeta
+
+	// The metadata as it was last read from or written to disk.
+	base *meta.PersistentMeta
+
+	// Set for frame sequences, which don't exist as a single file.
+	seq bool
 }
 
 // Marshaling JSON manually to avoid recursion. There is probably a more elegant
 // way of doing this.
 func (o TeflonObject) MarshalJSON() ([]byte, error) {
 	m := map[string]interface{}{
-		"Show":      o.Show.GetPath(),
-		"Path":      o.Path,
-		"Parent":    o.Parent.GetPath(),
-		"FileInfo":  o.FileInfo,
-		"ShowRoot":  o.ShowRoot,
-		"Contract":  o.Contract,
-		"Instances": o.Instances,
+		"Show":         o.Show.GetPath(),
+		"Path":         o.Path,
+		"Parent":       o.Parent.GetPath(),
+		"FileInfo":     o.FileInfo,
+		"Checksum":     o.Checksum,
+		"Verification": o.Verification,
+		"ShowRoot":     o.ShowRoot,
+		"Contract":     o.Contract,
+		"Instances":    o.Instances,
+		"Revision":     o.Revision,
+		"ImgInfo":      o.ImgInfo,
+		"Seq":          o.Seq,
 	}
 
-	for k, v := range o.UserData {
-		m[k] = v
+	// Namespaced keys are nested, so they can be selected like
+	// 'pipeline.publishId'. Keys are sorted, so a namespace always replaces a
+	// key with the same name.
+	im := o.InheritedMeta()
+	keys := []string{}
+	for k := range im {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		setNested(m, k, im[k].Value)
 	}
 
 	return json.Marshal(m)
@@ -111,15 +132,27 @@ func (o *TeflonObject) IMap() map[string]interface{} {
 			delete(m, k)
 		}
 	}
+	o.addDerived(m)
 
 	return m
 }
 
 // ChildrenNames() returns a slice of strings with the names of the children of the
-// object.
+// object. Frame files are not listed one by one, but as the sequences they belong
+// to.
 func (o *TeflonObject) ChildrenNames() (ch []string) {
+	if o.FileInfo.IsDir {
+		if ch, ok := o.indexedChildren(); ok {
+			return ch
+		}
+	}
+	return childrenFromDisk(o.Path)
+}
+
+// Reads the children names of a directory, grouping frames into sequences.
+func childrenFromDisk(fspath string) (ch []string) {
 	ch = []string{}
-	f, err := os.Open(o.Path)
+	f, err := os.Open(fspath)
 	defer f.Close()
 	if err != nil {
 		return ch
@@ -129,7 +162,7 @@ func (o *TeflonObject) ChildrenNames() (ch []string) {
 	if err != nil {
 		return ch
 	}
-	return ch
+	return groupSeqs(ch)
 }
 
 // Children() returns the child objects of the object.
@@ -146,6 +179,56 @@ func (o *TeflonObject) Children() (ch []*TeflonObject) {
 	return ch
 }
 
+// Walk() calls fn for the object and all of its descendants in depth first
+// order. The Teflon directories holding the metadata are skipped. If fn returns
+// an error the walk stops and returns it.
+func (o *TeflonObject) Walk(fn func(*TeflonObject) error) error {
+	if err := fn(o); err != nil {
+		return err
+	}
+	if !o.FileInfo.IsDir {
+		return nil
+	}
+	chn := o.ChildrenNames()
+	sort.Strings(chn)
+	for _, n := range chn {
+		if n == teflonDirName {
+			continue
+		}
+		ch, err := NewTeflonObject(filepath.Join(o.Path, n))
+		if err != nil {
+			return err
+		}
+		if err := ch.Walk(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WalkFiles() calls fn for the files among the object and its descendants.
+// Sequences are expanded to their frames, directories are skipped. If fn
+// returns an error the walk stops and returns it.
+func (o *TeflonObject) WalkFiles(fn func(*TeflonObject) error) error {
+	return o.Walk(func(o *TeflonObject) error {
+		switch {
+		case o.IsSeq():
+			for _, n := range o.Frames() {
+				f, err := NewTeflonObject(o.FramePath(n))
+				if err != nil {
+					return err
+				}
+				if err := fn(f); err != nil {
+					return err
+				}
+			}
+		case !o.FileInfo.IsDir:
+			return fn(o)
+		}
+		return nil
+	})
+}
+
 // MetaFile returns the file path to the TeflonObject's meta file. In the case of a
 // file it is:
 //   $DIR/.teflon/$FILE._
@@ -162,29 +245,162 @@ func (o *TeflonObject) MetaFile() string {
 // Sets an entry in the user section of the metadata.
 func (o *TeflonObject) SetMeta(key, value string) {
 	o.UserData[key] = value
+	o.Unset = remove(o.Unset, key)
 }
 
-// Deletes an entry from the user section of the metadata.
+// Deletes an entry from the user section of the metadata. After deletion the
+// object inherits the key from its ancestors again.
 func (o *TeflonObject) DelMeta(key string) {
 	delete(o.UserData, key)
+	o.Unset = remove(o.Unset, key)
 }
 
-// SincMeta() writes metadata to disk.
+// SincMeta() writes metadata to its store. If the show has a schema the metadata
+// is checked first, and a *ValidationError is returned if it's invalid. The
+// metadata is locked during the write and meta files are replaced atomically,
+// so readers never see a partially written file.
 func (o *TeflonObject) SyncMeta() error {
-	out, err := protobuf.Marshal(o)
+	l, err := o.lockMeta()
 	if err != nil {
 		return err
 	}
+	err = o.writeMeta()
+	l.Unlock()
+	if err == nil {
+		o.updateIndexed()
+	}
+	return err
+}
 
-	o.createTeflonDir()
+// UpdateMeta() does a locked read-modify-write cycle on the object's metadata.
+// It locks the metadata, reloads it from its store, calls fn to modify it, and
+// writes the result back. Concurrent updates of different keys of the same
+// object are merged this way instead of overwriting each other.
+func (o *TeflonObject) UpdateMeta(fn func(*TeflonObject) error) error {
+	l, err := o.lockMeta()
+	if err != nil {
+		return err
+	}
+	err = o.loadMeta()
+	if err == nil {
+		err = fn(o)
+	}
+	if err == nil {
+		err = o.writeMeta()
+	}
+	l.Unlock()
+	if err == nil {
+		o.updateIndexed()
+	}
+	return err
+}
 
-	err = ioutil.WriteFile(o.MetaFile(), out, 0644)
+// Writes the metadata to its store. The caller has to hold the lock of the
+// metadata.
+func (o *TeflonObject) writeMeta() error {
+	vs, err := o.CheckMeta()
 	if err != nil {
 		return err
 	}
+	if len(vs) > 0 {
+		return &ValidationError{Violations: vs}
+	}
+
+	st, err := o.MetaStore()
+	if err != nil {
+		return err
+	}
+
+	// Keep the previous state for the journal and check that nobody has written
+	// the metadata since we've read it.
+	old := &meta.PersistentMeta{}
+	if st.Has(o) {
+		if err := readMeta(st, o, old); err != nil {
+			if _, ok := err.(*VersionError); ok {
+				return err
+			}
+			log.Println("WARNING: Couldn't read previous metadata:", err)
+		} else if old.Revision != o.Revision {
+			return &ConflictError{Path: o.Path, Revision: o.Revision, DiskRevision: old.Revision}
+		}
+	}
+	if err := o.checkWrite(old); err != nil {
+		return err
+	}
+
+	c, err := o.Config()
+	if err != nil {
+		return err
+	}
+
+	rev := o.Revision
+	o.Revision = old.Revision + 1
+	o.Version = MetaVersion()
+	if err := st.Write(o, &o.PersistentMeta, c.MetaFormat); err != nil {
+		o.Revision = rev
+		return err
+	}
+	o.base = protobuf.Clone(&o.PersistentMeta).(*meta.PersistentMeta)
+
+	if err := o.journal(old.UserData); err != nil {
+		log.Println("WARNING: Couldn't write journal:", err)
+	}
 	return nil
 }
 
+// Loads the metadata of the object from its store, replacing the metadata in
+// memory. Missing metadata results in empty metadata.
+func (o *TeflonObject) loadMeta() error {
+	st, err := o.MetaStore()
+	if err != nil {
+		return err
+	}
+	o.PersistentMeta.Reset()
+	if st.Has(o) {
+		if err := readMeta(st, o, &o.PersistentMeta); err != nil {
+			return err
+		}
+	}
+
+	// Init UserData if not exists
+	if o.UserData == nil {
+		o.UserData = make(map[string]string)
+	}
+
+	// The frame range on disk is always the truth.
+	if o.seq {
+		s, _, err := readSeq(o.Path)
+		if err != nil {
+			return err
+		}
+		o.Seq = s
+	}
+	o.base = protobuf.Clone(&o.PersistentMeta).(*meta.PersistentMeta)
+	return nil
+}
+
+// Reads the metadata of an object from a store, and upgrades it to the current
+// format version.
+func readMeta(st MetaStore, o *TeflonObject, pm *meta.PersistentMeta) error {
+	if err := st.Read(o, pm); err != nil {
+		return err
+	}
+	return migrateMeta(pm)
+}
+
+// Reads and decodes a meta file of any format, and upgrades it to the current
+// format version.
+func readMetaFile(m string, pm *meta.PersistentMeta) error {
+	in, err := ioutil.ReadFile(m)
+	if err != nil {
+		return err
+	}
+	if err := decodeMeta(in, pm); err != nil {
+		return err
+	}
+	return migrateMeta(pm)
+}
+
 // Creates the Teflon directory for the object's meta file.
 func (o TeflonObject) createTeflonDir() error {
 	err := os.Mkdir(filepath.Dir(o.MetaFile()), 0755)
@@ -197,6 +413,30 @@ func (o TeflonObject) createTeflonDir() error {
 	return nil
 }
 
+// Initializes the file info and the metadata of the object from the disk.
+func (o *TeflonObject) initFromDisk() error {
+	stat, err := os.Stat(o.Path)
+	if os.IsNotExist(err) {
+		// Frame sequences don't exist as a single file.
+		if serr := o.initSeq(); serr != os.ErrNotExist {
+			err = serr
+		}
+	} else if err == nil {
+		modtime, _ := ptypes.TimestampProto(stat.ModTime())
+		o.FileInfo = meta.FileInfo{
+			Name:    stat.Name(),
+			Size:    stat.Size(),
+			Mode:    uint32(stat.Mode()),
+			ModTime: modtime,
+			IsDir:   stat.IsDir(),
+		}
+
+		// Read meta file if exists
+		err = o.loadMeta()
+	}
+	return err
+}
+
 // Helper function to get empty string for nil objects during JSON marshaling.
 func (o *TeflonObject) GetPath() string {
 	if o != nil {
@@ -237,7 +477,7 @@ func Path(target string) (string, error) {
 // can not be made, the function reurns the original string.
 func ShowAbs(fspath string) string {
 	o, err := NewTeflonObject(fspath)
-	if err != nil {
+	if err != nil || o.Show == nil {
 		return fspath
 	}
 	return strings.Replace(fspath, o.Show.Path, "/", 1)
@@ -273,53 +513,28 @@ func NewTeflonObject(target string) (*TeflonObject, error) {
 		o = &TeflonObject{Path: fspath}
 	}
 
-	// Initialize metadata
-	stat, err := os.Stat(o.Path)
-	if err != nil {
-		return nil, err
-	}
-	modtime, _ := ptypes.TimestampProto(stat.ModTime())
-	o.FileInfo = meta.FileInfo{
-		Name:    stat.Name(),
-		Size:    stat.Size(),
-		Mode:    uint32(stat.Mode()),
-		ModTime: modtime,
-		IsDir:   stat.IsDir(),
-	}
-	m := o.MetaFile()
-
-	// Read meta file if exists
-	if _, err := os.Stat(m); !os.IsNotExist(err) {
-		in, err := ioutil.ReadFile(m)
-		if err != nil {
-			return nil, err
-		}
-		err = protobuf.Unmarshal(in, o)
+	// The parent comes first, since the show decides where the metadata is
+	// stored.
+	if parent := filepath.Dir(o.Path); parent != "/" {
+		p, err := NewTeflonObject(parent)
 		if err != nil {
 			return nil, err
 		}
+		o.Parent = p
+		o.Show = p.Show
 	}
 
-	// Init UserData if not exists
-	if o.UserData == nil {
-		o.UserData = make(map[string]string)
+	// Initialize metadata, from the index of the show if it has one.
+	if !o.loadIndexed() {
+		if err := o.initFromDisk(); err != nil {
+			return nil, err
+		}
 	}
 
 	// Check if it is show root
 	if o.ShowRoot {
 		o.Show = o
-	} else {
-		parent := filepath.Dir(o.Path)
-
-		// Check if reached file-system root
-		if parent != "/" {
-			p, err := NewTeflonObject(parent)
-			if err != nil {
-				return nil, err
-			}
-			o.Parent = p
-			o.Show = p.Show
-		}
+		o.Parent = nil
 	}
 
 	Objects[fspath] = o
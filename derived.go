@@ -0,0 +1,92 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Parses the derived fields of the config. The parsed expressions are kept in
+// the config, so every expression is parsed only once.
+func (c *ShowConfig) derivedExprs() (map[string]*Expr, error) {
+	if c.derived != nil || len(c.Derived) == 0 {
+		return c.derived, nil
+	}
+	exs := map[string]*Expr{}
+	for k, s := range c.Derived {
+		ex, err := NewExpr(s + "@")
+		if err != nil || ex.MetaSelector == nil {
+			return nil, fmt.Errorf("Couldn't parse derived field %s: %s", k, s)
+		}
+		exs[k] = ex
+	}
+	c.derived = exs
+	return exs, nil
+}
+
+// IsDerived() tells if the key is a derived field in the show of the object.
+// Derived fields can't be set.
+func (o *TeflonObject) IsDerived(key string) bool {
+	c, err := o.Config()
+	if err != nil {
+		return false
+	}
+	for k := range c.Derived {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Adds the derived fields of the show to the IMap of the object. Derived fields
+// hide stored values with the same key. Fields can refer to other derived
+// fields, so they are evaluated in rounds until no more can be evaluated.
+// Fields whose inputs are missing are left out.
+func (o *TeflonObject) addDerived(m map[string]interface{}) {
+	c, err := o.Config()
+	if err != nil {
+		return
+	}
+	exs, err := c.derivedExprs()
+	if err != nil || len(exs) == 0 {
+		return
+	}
+
+	todo := []string{}
+	for k := range exs {
+		delNested(m, k)
+		todo = append(todo, k)
+	}
+	sort.Strings(todo)
+
+	ctx := &Context{Dir: o, IMap: m}
+	for len(todo) > 0 {
+		rest := []string{}
+		for _, k := range todo {
+			v, err := exs[k].MetaSelector.Eval(ctx)
+			if err != nil || v == nil {
+				rest = append(rest, k)
+				continue
+			}
+			setNested(m, k, v)
+		}
+		if len(rest) == len(todo) {
+			break
+		}
+		todo = rest
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/gradient-images/teflon/internal/meta"
+
+	protobuf "github.com/golang/protobuf/proto"
+)
+
+// Kinds of key differences.
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// KeyDiff is the difference of a single key between two metadata maps. Keys of
+// nested maps are dotted, like 'ImgInfo.Width'. Old is unset for added keys,
+// New is unset for removed ones.
+type KeyDiff struct {
+	Key  string
+	Kind string
+	Old  interface{} `json:",omitempty"`
+	New  interface{} `json:",omitempty"`
+}
+
+func (d KeyDiff) String() string {
+	switch d.Kind {
+	case DiffAdded:
+		return fmt.Sprintf("+ %s: %s", d.Key, diffValue(d.New))
+	case DiffRemoved:
+		return fmt.Sprintf("- %s: %s", d.Key, diffValue(d.Old))
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", d.Key, diffValue(d.Old), diffValue(d.New))
+}
+
+// Formats a value of a KeyDiff as JSON.
+func diffValue(v interface{}) string {
+	j, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(j)
+}
+
+// DiffMaps() compares two metadata maps, like the IMaps of two objects, key by
+// key. Nested maps are compared recursively, all other values, including lists,
+// as a whole. The result is sorted by key.
+func DiffMaps(a, b map[string]interface{}) []KeyDiff {
+	ds := []KeyDiff{}
+	diffMaps("", a, b, &ds)
+	sort.Slice(ds, func(i, j int) bool { return ds[i].Key < ds[j].Key })
+	return ds
+}
+
+func diffMaps(prefix string, a, b map[string]interface{}, ds *[]KeyDiff) {
+	for k, av := range a {
+		bv, ok := b[k]
+		switch {
+		case !ok:
+			*ds = append(*ds, KeyDiff{Key: prefix + k, Kind: DiffRemoved, Old: av})
+		case reflect.DeepEqual(av, bv):
+		default:
+			am, aok := av.(map[string]interface{})
+			bm, bok := bv.(map[string]interface{})
+			if aok && bok {
+				diffMaps(prefix+k+".", am, bm, ds)
+			} else {
+				*ds = append(*ds, KeyDiff{Key: prefix + k, Kind: DiffChanged, Old: av, New: bv})
+			}
+		}
+	}
+	for k, bv := range b {
+		if _, ok := a[k]; !ok {
+			*ds = append(*ds, KeyDiff{Key: prefix + k, Kind: DiffAdded, New: bv})
+		}
+	}
+}
+
+// DiffIgnore lists the IMap keys that tell objects apart rather than describe
+// them. They're left out by Diff() unless all is set.
+var DiffIgnore = []string{"Path", "Parent", "Show", "FileInfo", "Revision"}
+
+// Diff() compares the IMap of the object with the IMap of another object. The
+// keys in DiffIgnore are left out unless all is set.
+func (o *TeflonObject) Diff(other *TeflonObject, all bool) []KeyDiff {
+	a, b := o.IMap(), other.IMap()
+	if !all {
+		for _, k := range DiffIgnore {
+			delete(a, k)
+			delete(b, k)
+		}
+	}
+	return DiffMaps(a, b)
+}
+
+// Returned by the update function of ChangeMeta() to skip writing.
+var errNoChange = errors.New("No change")
+
+// ChangeMeta() is UpdateMeta() that reports the changes fn makes to the stored
+// metadata. Nothing is written if fn changes nothing. With dryRun the changes
+// are only reported, and the metadata of the object is left as it was.
+func (o *TeflonObject) ChangeMeta(fn func(*TeflonObject) error, dryRun bool) ([]KeyDiff, error) {
+	var ds []KeyDiff
+	change := func(o *TeflonObject) error {
+		before := protobuf.Clone(&o.PersistentMeta).(*meta.PersistentMeta)
+		if err := fn(o); err != nil {
+			return err
+		}
+		a, err := metaMap(before)
+		if err != nil {
+			return err
+		}
+		b, err := metaMap(&o.PersistentMeta)
+		if err != nil {
+			return err
+		}
+		ds = DiffMaps(a, b)
+		if dryRun {
+			err := o.checkWrite(before)
+			o.PersistentMeta = *before
+			if err != nil {
+				return err
+			}
+		}
+		if dryRun || len(ds) == 0 {
+			return errNoChange
+		}
+		return nil
+	}
+
+	// Dry runs don't lock, so they don't touch the store at all.
+	var err error
+	if dryRun {
+		if err = o.loadMeta(); err == nil {
+			err = change(o)
+		}
+	} else {
+		err = o.UpdateMeta(change)
+	}
+	if err == errNoChange {
+		err = nil
+	}
+	return ds, err
+}
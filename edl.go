@@ -0,0 +1,395 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EDL is a parsed CMX3600 edit decision list. Only video events are kept.
+type EDL struct {
+	Title     string
+	DropFrame bool
+	Events    []EDLEvent
+}
+
+// EDLEvent is a single event of an EDL. Timecodes are kept as they're written.
+// For transitions only the incoming clip is kept.
+type EDLEvent struct {
+	Num        int
+	Reel       string
+	Track      string
+	Transition string
+	SrcIn      string
+	SrcOut     string
+	RecIn      string
+	RecOut     string
+	Clip       string
+	Loc        string
+}
+
+var (
+	edlEventRx = regexp.MustCompile(`^(\d+)\s+(\S+)\s+(\S+)\s+(\S+)\s+(?:(\d+)\s+)?` +
+		`(\d\d[:;]\d\d[:;]\d\d[:;]\d\d)\s+(\d\d[:;]\d\d[:;]\d\d[:;]\d\d)\s+` +
+		`(\d\d[:;]\d\d[:;]\d\d[:;]\d\d)\s+(\d\d[:;]\d\d[:;]\d\d[:;]\d\d)`)
+	edlLocRx = regexp.MustCompile(`^\*\s*LOC:\s*\S+\s+\S+\s+(\S+)`)
+)
+
+// ParseEDL() parses a CMX3600 EDL. The clip name is taken from the
+// '* FROM CLIP NAME:' comment, or the '* TO CLIP NAME:' comment of
+// transitions, the shot name from the last word of a '* LOC:'
+// comment of the event.
+func ParseEDL(r io.Reader) (*EDL, error) {
+	e := &EDL{}
+	var last *EDLEvent
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		l := strings.TrimSpace(sc.Text())
+		switch {
+		case l == "":
+		case strings.HasPrefix(l, "TITLE:"):
+			e.Title = strings.TrimSpace(strings.TrimPrefix(l, "TITLE:"))
+		case strings.HasPrefix(l, "FCM:"):
+			e.DropFrame = strings.Contains(l, "DROP") && !strings.Contains(l, "NON")
+		case strings.HasPrefix(l, "*"):
+			if last == nil {
+				continue
+			}
+			// The clip of a transition is the TO clip, its FROM clip is the
+			// outgoing one.
+			if m := edlLocRx.FindStringSubmatch(l); m != nil {
+				last.Loc = m[1]
+			} else if strings.Contains(l, "TO CLIP NAME:") {
+				last.Clip = strings.TrimSpace(l[strings.Index(l, "TO CLIP NAME:")+13:])
+			} else if strings.Contains(l, "FROM CLIP NAME:") && last.Transition == "C" {
+				last.Clip = strings.TrimSpace(l[strings.Index(l, "FROM CLIP NAME:")+15:])
+			}
+		default:
+			m := edlEventRx.FindStringSubmatch(l)
+			if m == nil {
+				// Motion effects, split edits and alike.
+				continue
+			}
+			n, _ := strconv.Atoi(m[1])
+			ev := EDLEvent{
+				Num:        n,
+				Reel:       m[2],
+				Track:      m[3],
+				Transition: m[4],
+				SrcIn:      m[6],
+				SrcOut:     m[7],
+				RecIn:      m[8],
+				RecOut:     m[9],
+			}
+			if !strings.HasPrefix(ev.Track, "V") && !strings.HasPrefix(ev.Track, "B") {
+				last = nil
+				continue
+			}
+			// The second line of a transition replaces the outgoing clip.
+			if l := len(e.Events); l > 0 && e.Events[l-1].Num == n {
+				e.Events[l-1] = ev
+			} else {
+				e.Events = append(e.Events, ev)
+			}
+			last = &e.Events[len(e.Events)-1]
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(e.Events) == 0 {
+		return nil, errors.New("No video events found.")
+	}
+	return e, nil
+}
+
+// TCToFrames() converts a timecode to a frame count. Drop frame timecodes are
+// supported for 30 and 60 fps.
+func TCToFrames(tc string, fps int, dropFrame bool) (int, error) {
+	f := strings.FieldsFunc(tc, func(r rune) bool { return r == ':' || r == ';' })
+	if len(f) != 4 {
+		return 0, errors.New("Malformed timecode: " + tc)
+	}
+	v := [4]int{}
+	for i, s := range f {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, errors.New("Malformed timecode: " + tc)
+		}
+		v[i] = n
+	}
+	h, m, s, fr := v[0], v[1], v[2], v[3]
+	frames := ((h*60+m)*60+s)*fps + fr
+	if dropFrame && fps%30 == 0 {
+		drop := fps / 15
+		mins := h*60 + m
+		frames -= drop * (mins - mins/10)
+	}
+	return frames, nil
+}
+
+// CutOptions controls how EDL events are turned into shots.
+type CutOptions struct {
+	FPS     int
+	Handles int
+	// Start is the first frame of the shots including handles.
+	Start int
+	// Prefix of generated shot names.
+	Prefix string
+}
+
+// CutShot is a shot of a cut with the metadata describing it. Generated names
+// come from the position of the shot in the cut, not from the EDL.
+type CutShot struct {
+	Name      string
+	Generated bool
+	Meta      map[string]string
+}
+
+// Keys of the cut metadata that tell if a shot was retimed.
+var cutTimingKeys = []string{"srcIn", "srcOut", "duration", "cutIn", "cutOut", "handles"}
+
+// Shots() converts the events of the EDL to shots in record order. Shots are
+// named by the '* LOC:' comment of their event if they have one, and by their
+// position in the cut, like 'sh010', 'sh020', otherwise. ImportCut() keeps the
+// names of existing shots with generated names. The metadata of a shot
+// holds its reel, clip name, source and record timecodes, the in and out frames
+// of the cut and of the handles, the duration and the order in the cut.
+func (e *EDL) Shots(opts CutOptions) ([]CutShot, error) {
+	evs := append([]EDLEvent{}, e.Events...)
+	var err error
+	recIn := func(ev EDLEvent) int {
+		f, ferr := TCToFrames(ev.RecIn, opts.FPS, e.DropFrame)
+		if ferr != nil {
+			err = ferr
+		}
+		return f
+	}
+	sort.SliceStable(evs, func(i, j int) bool { return recIn(evs[i]) < recIn(evs[j]) })
+	if err != nil {
+		return nil, err
+	}
+
+	shots := []CutShot{}
+	names := map[string]bool{}
+	for i, ev := range evs {
+		si, err := TCToFrames(ev.SrcIn, opts.FPS, e.DropFrame)
+		if err != nil {
+			return nil, err
+		}
+		so, err := TCToFrames(ev.SrcOut, opts.FPS, e.DropFrame)
+		if err != nil {
+			return nil, err
+		}
+		dur := so - si
+		if dur <= 0 {
+			return nil, fmt.Errorf("Event %d has no duration.", ev.Num)
+		}
+
+		name := ev.Loc
+		if name == "" {
+			name = fmt.Sprintf("%s%03d", opts.Prefix, (i+1)*10)
+		}
+		if names[name] || name == "." || name == ".." || strings.ContainsRune(name, filepath.Separator) {
+			return nil, fmt.Errorf("Invalid or duplicate shot name in event %d: %s", ev.Num, name)
+		}
+		names[name] = true
+
+		cutIn := opts.Start + opts.Handles
+		cutOut := cutIn + dur - 1
+		shots = append(shots, CutShot{
+			Name:      name,
+			Generated: ev.Loc == "",
+			Meta: map[string]string{
+				"event":    strconv.Itoa(ev.Num),
+				"reel":     ev.Reel,
+				"clip":     ev.Clip,
+				"srcIn":    ev.SrcIn,
+				"srcOut":   ev.SrcOut,
+				"recIn":    ev.RecIn,
+				"recOut":   ev.RecOut,
+				"duration": strconv.Itoa(dur),
+				"handles":  strconv.Itoa(opts.Handles),
+				"cutIn":    strconv.Itoa(cutIn),
+				"cutOut":   strconv.Itoa(cutOut),
+				"headIn":   strconv.Itoa(cutIn - opts.Handles),
+				"tailOut":  strconv.Itoa(cutOut + opts.Handles),
+				"order":    strconv.Itoa(i + 1),
+				"fps":      strconv.Itoa(opts.FPS),
+			},
+		})
+	}
+	return shots, nil
+}
+
+// CutDiff is the difference between the shots of a cut and the shots under an
+// object.
+type CutDiff struct {
+	Added     []string
+	Removed   []string
+	Retimed   []string
+	Unchanged []string
+}
+
+// ImportCut() creates the shots of a cut under the object through
+// CreateObject(), and sets or updates their cut metadata. Shots are the
+// directories under the object that have a 'cutIn' key. Shots missing from
+// the cut are only reported as removed, they're not touched. With dryRun only
+// the difference is computed.
+func (o *TeflonObject) ImportCut(shots []CutShot, dryRun bool) (*CutDiff, error) {
+	if !o.FileInfo.IsDir {
+		return nil, errors.New("Not a directory: " + o.Path)
+	}
+	d := &CutDiff{}
+
+	existing := map[string]*TeflonObject{}
+	taken := map[string]bool{}
+	for _, ch := range o.Children() {
+		taken[ch.FileInfo.Name] = true
+		if _, ok := ch.UserData["cutIn"]; ok && ch.FileInfo.IsDir {
+			existing[ch.FileInfo.Name] = ch
+		}
+	}
+	names := cutNames(shots, existing, taken)
+
+	for i, s := range shots {
+		name := names[i]
+		so, ok := existing[name]
+		delete(existing, name)
+		switch {
+		case !ok:
+			d.Added = append(d.Added, name)
+		case cutRetimed(so, s):
+			d.Retimed = append(d.Retimed, name)
+		default:
+			d.Unchanged = append(d.Unchanged, name)
+		}
+		if dryRun {
+			continue
+		}
+
+		if !ok && !Exist(filepath.Join(o.Path, name)) {
+			if _, err := o.CreateObject(name, false); err != nil {
+				return nil, err
+			}
+		}
+		so, err := NewTeflonObject(filepath.Join(o.Path, name))
+		if err != nil {
+			return nil, err
+		}
+		err = so.UpdateMeta(func(so *TeflonObject) error {
+			for k, v := range s.Meta {
+				so.SetMeta(k, v)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for n := range existing {
+		d.Removed = append(d.Removed, n)
+	}
+	sort.Strings(d.Removed)
+	return d, nil
+}
+
+// Keys identifying the source of a shot, the exact one first.
+var cutSourceKeys = [][]string{{"reel", "clip", "srcIn"}, {"reel", "clip"}}
+
+// Returns the names of the shots of a cut under an object. Shots with generated
+// names are matched to the existing shots by their source, so inserting a shot
+// into a cut doesn't rename the shots after it. Unchanged sources are matched
+// before retimed ones. New shots get their generated name, or the next free
+// number after it.
+func cutNames(shots []CutShot, existing map[string]*TeflonObject, taken map[string]bool) []string {
+	names := make([]string, len(shots))
+	claimed := map[string]bool{}
+	for i, s := range shots {
+		if !s.Generated {
+			names[i] = s.Name
+			claimed[s.Name] = true
+		}
+	}
+
+	ens := []string{}
+	for n := range existing {
+		ens = append(ens, n)
+	}
+	sort.Strings(ens)
+	for _, keys := range cutSourceKeys {
+		for i, s := range shots {
+			if names[i] != "" {
+				continue
+			}
+			for _, n := range ens {
+				if !claimed[n] && sameSource(existing[n], s, keys) {
+					names[i] = n
+					claimed[n] = true
+					break
+				}
+			}
+		}
+	}
+
+	for i, s := range shots {
+		if names[i] != "" {
+			continue
+		}
+		n := s.Name
+		for taken[n] || claimed[n] {
+			n = nextName(n)
+		}
+		names[i] = n
+		claimed[n] = true
+	}
+	return names
+}
+
+// Tells if the keys of a shot are the same in the cut.
+func sameSource(o *TeflonObject, s CutShot, keys []string) bool {
+	for _, k := range keys {
+		if o.UserData[k] != s.Meta[k] {
+			return false
+		}
+	}
+	return true
+}
+
+var trailingNumRx = regexp.MustCompile(`\d+$`)
+
+// Increments the number at the end of a name keeping its padding, like
+// 'sh020' to 'sh021'. Names without a number get one.
+func nextName(name string) string {
+	m := trailingNumRx.FindString(name)
+	if m == "" {
+		return name + "_2"
+	}
+	n, _ := strconv.Atoi(m)
+	return fmt.Sprintf("%s%0*d", name[:len(name)-len(m)], len(m), n+1)
+}
+
+// Tells if the timing of a shot differs from the cut.
+func cutRetimed(o *TeflonObject, s CutShot) bool {
+	return !sameSource(o, s, cutTimingKeys)
+}
@@ -18,7 +18,6 @@ package teflon
 
 import (
   "log"
-  "path/filepath"
   )
 
 // EventType is an enum describing the type of a Teflon event.
@@ -32,36 +31,45 @@ const (
 type Event struct {
 	Object *TeflonObject
 	Type   EventType
-  Result chan *TeflonObject
+  Result chan *EventResult
+}
+
+// EventResult is sent back on the Result channel of an event when it's handled.
+type EventResult struct {
+  Object *TeflonObject
+  // Contracts are the contract objects executed for the event.
+  Contracts []*TeflonObject
+  Err       error
 }
 
 var Events chan Event = make(chan Event)
 var Done chan bool = make(chan bool)
 
+// Contracts are executed on new objects after they are created. The actions of
+// contracts mustn't send events, since events are handled one by one.
 func listen() {
   for evt := range Events {
     log.Printf("EVENT: Event received: %s (%s)", evt.Object.GetPath(), evt.Type)
 
-    p := evt.Object
-    for {
-      p = p.Parent
-      cfs := filepath.Join(p.Path, contractDirName)
-      var c *TeflonObject
-      var err error
-      if Exist(cfs) {
-        c, err = NewTeflonObject(cfs)
-        if err != nil {
-          log.Fatalln("ABORT: Can't create contract dir object:", err)
+    res := &EventResult{Object: evt.Object}
+    if evt.Type == PostNew {
+      var cs []*TeflonObject
+      cs, res.Err = evt.Object.Contracts()
+      for _, c := range cs {
+        if res.Err != nil {
+          break
+        }
+        log.Println("DEBUG: Executing contract:", c.Path)
+        if res.Err = evt.Object.ExecContract(c); res.Err == nil {
+          res.Contracts = append(res.Contracts, c)
         }
-        log.Println("DEBUG: Created contract dir object:", c.Path)
-      }
-      if p.ShowRoot {
-        break
       }
     }
 
     if evt.Result != nil {
-      evt.Result <- evt.Object
+      evt.Result <- res
+    } else if res.Err != nil {
+      log.Println("WARNING: Couldn't execute contracts:", res.Err)
     }
   }
   Done <- true
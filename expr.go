@@ -15,7 +15,10 @@
 
 package teflon
 
-import "errors"
+import (
+	"errors"
+	"strconv"
+)
 
 // ENode is the building block of the AST. The meta selector and the object
 // selector both implemeted as a chain of ENodes, only the evaluation is different.
@@ -100,6 +103,49 @@ func (ex *Expr) Generate(c *Context) (res []string, err error) {
 	return res, nil
 }
 
+// Objects evaluates the object selector of the expression and returns the
+// matching objects. Without an object selector the context object is returned.
+func (ex *Expr) Objects(c *Context) (res []*TeflonObject, err error) {
+	if ex.MetaSelector != nil {
+		return nil, errors.New("Meta selector is not allowed in object expressions.")
+	}
+	if ex.ObjectSelector == nil {
+		return []*TeflonObject{c.Dir}, nil
+	}
+	for {
+		o := ex.ObjectSelector.NextMatch(c.Dir)
+		if o == nil {
+			break
+		}
+		res = append(res, o)
+	}
+	return res, nil
+}
+
+// EvalMeta() evaluates a meta selector, like 'cutOut-cutIn+1', in the context
+// of the object, and returns the result as a user metadata value. Numbers are
+// formatted without trailing zeros, other non-string values are JSON encoded.
+func (o *TeflonObject) EvalMeta(exs string) (string, error) {
+	ex, err := NewExpr(exs + "@")
+	if err != nil {
+		return "", err
+	}
+	if ex.MetaSelector == nil {
+		return "", errors.New("Not a meta selector: " + exs)
+	}
+	v, err := ex.MetaSelector.Eval(&Context{Dir: o, IMap: o.IMap()})
+	if err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case nil:
+		return "", errors.New("Expression has no value: " + exs)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	}
+	return cellValue(v)
+}
+
 func (ex *Expr) String() string {
 	return ex.text
 }
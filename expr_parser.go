@@ -679,14 +679,27 @@ var g = &grammar{
 			expr: &actionExpr{
 				pos: position{line: 157, col: 9, offset: 3353},
 				run: (*parser).callonName1,
-				expr: &oneOrMoreExpr{
+				expr: &seqExpr{
 					pos: position{line: 157, col: 9, offset: 3353},
-					expr: &charClassMatcher{
-						pos:        position{line: 157, col: 9, offset: 3353},
-						val:        "[\\pL]",
-						classes:    []*unicode.RangeTable{rangeTable("L")},
-						ignoreCase: false,
-						inverted:   false,
+					exprs: []interface{}{
+						&charClassMatcher{
+							pos:        position{line: 157, col: 9, offset: 3353},
+							val:        "[\\pL]",
+							classes:    []*unicode.RangeTable{rangeTable("L")},
+							ignoreCase: false,
+							inverted:   false,
+						},
+						&zeroOrMoreExpr{
+							pos: position{line: 157, col: 14, offset: 3358},
+							expr: &charClassMatcher{
+								pos:        position{line: 157, col: 14, offset: 3358},
+								val:        "[\\pL\\pN_]",
+								chars:      []rune{'_'},
+								classes:    []*unicode.RangeTable{rangeTable("L"), rangeTable("N")},
+								ignoreCase: false,
+								inverted:   false,
+							},
+						},
 					},
 				},
 			},
@@ -1277,7 +1290,8 @@ func (p *parser) callonName1() (interface{}, error) {
 func (c *current) onString1() (interface{}, error) {
 	// TODO : the forward slash (solidus) is not a valid escape in Go, it will
 	// fail if there's one in the string
-	return strconv.Unquote(string(c.text))
+	s, err := strconv.Unquote(string(c.text))
+	return &StringNode{Value: s}, err
 }
 
 func (p *parser) callonString1() (interface{}, error) {
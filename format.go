@@ -0,0 +1,146 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gradient-images/teflon/internal/meta"
+
+	"github.com/golang/protobuf/jsonpb"
+	protobuf "github.com/golang/protobuf/proto"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Meta file formats.
+const (
+	FormatProtobuf = "protobuf"
+	FormatJSON     = "json"
+	FormatYAML     = "yaml"
+)
+
+// Meta files in YAML format start with a document start marker, which makes
+// them distinguishable from the other formats.
+const yamlHeader = "---\n"
+
+// Marshals metadata into the given format. JSON output is canonical: fields and
+// map keys are sorted and the output is indented, so it can be diffed and
+// edited by hand.
+func encodeMeta(pm *meta.PersistentMeta, format string) ([]byte, error) {
+	switch format {
+	case "", FormatProtobuf:
+		return protobuf.Marshal(pm)
+	case FormatJSON:
+		return canonicalJSON(pm)
+	case FormatYAML:
+		j, err := canonicalJSON(pm)
+		if err != nil {
+			return nil, err
+		}
+		var v yaml.MapSlice
+		if err := yaml.Unmarshal(j, &v); err != nil {
+			return nil, err
+		}
+		out, err := yaml.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(yamlHeader), out...), nil
+	}
+	return nil, errors.New("Unknown meta format: " + format)
+}
+
+// Unmarshals metadata in any of the supported formats.
+func decodeMeta(in []byte, pm *meta.PersistentMeta) error {
+	switch sniffFormat(in) {
+	case FormatJSON:
+		return jsonpb.Unmarshal(bytes.NewReader(in), pm)
+	case FormatYAML:
+		var v interface{}
+		if err := yaml.Unmarshal(in, &v); err != nil {
+			return err
+		}
+		j, err := json.Marshal(jsonCompatible(v))
+		if err != nil {
+			return err
+		}
+		return jsonpb.Unmarshal(bytes.NewReader(j), pm)
+	}
+	return protobuf.Unmarshal(in, pm)
+}
+
+// Tells the format of encoded metadata. Binary protobuf metadata never starts
+// with the characters checked here, since those would be invalid field keys.
+func sniffFormat(in []byte) string {
+	t := bytes.TrimLeft(in, " \t\r\n")
+	if len(t) == 0 {
+		return FormatProtobuf
+	}
+	switch t[0] {
+	case '{':
+		return FormatJSON
+	case '-', '#':
+		return FormatYAML
+	}
+	return FormatProtobuf
+}
+
+func canonicalJSON(pm *meta.PersistentMeta) ([]byte, error) {
+	m := jsonpb.Marshaler{OrigName: true, Indent: "  "}
+	s, err := m.MarshalToString(pm)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(s + "\n"), nil
+}
+
+// Converts the maps decoded by the YAML package to maps with string keys.
+func jsonCompatible(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, e := range v {
+			m[fmt.Sprint(k)] = jsonCompatible(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range v {
+			v[i] = jsonCompatible(e)
+		}
+	}
+	return v
+}
+
+// ConvertMeta() rewrites the object's metadata in the given format. The
+// metadata itself doesn't change. Objects without metadata are left alone.
+func (o *TeflonObject) ConvertMeta(format string) error {
+	st, err := o.MetaStore()
+	if err != nil || !st.Has(o) {
+		return err
+	}
+	l, err := st.Lock(o)
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	pm := &meta.PersistentMeta{}
+	if err := readMeta(st, o, pm); err != nil {
+		return err
+	}
+	return st.Write(o, pm, format)
+}
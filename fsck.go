@@ -0,0 +1,276 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gradient-images/teflon/internal/meta"
+)
+
+// Problem codes reported by Fsck(). The codes are stable, scripts can rely on
+// them.
+const (
+	FsckOrphanMeta       = "F001" // Meta file of a non-existent object.
+	FsckCorruptMeta      = "F002" // Meta file that can't be decoded.
+	FsckDanglingInstance = "F003" // Instances entry of a non-existent object.
+	FsckNestedShowRoot   = "F004" // Show root inside another show.
+	FsckStaleLock        = "F005" // Lock file left behind by a crashed process.
+	FsckStaleTemp        = "F006" // Temporary file of an interrupted write.
+)
+
+// FsckProblem is a single problem found by Fsck().
+type FsckProblem struct {
+	Code     string
+	Path     string
+	Msg      string
+	Repaired bool
+}
+
+func (p FsckProblem) String() string {
+	s := fmt.Sprintf("%s %s: %s", p.Code, p.Path, p.Msg)
+	if p.Repaired {
+		s += " (repaired)"
+	}
+	return s
+}
+
+// Fsck checks the consistency of the meta files under a file-system path. It
+// reads the meta files directly instead of creating objects, so it can get past
+// corrupt files. If repair is set, orphaned meta files, stale lock and temporary
+// files are deleted, dangling Instances entries are pruned and corrupt meta
+// files are moved aside with a '.corrupt' extension. Nested show roots are only
+// reported. Outside of a show every show root found under root is checked as a
+// show of its own.
+func Fsck(root string, repair bool) ([]FsckProblem, error) {
+	f := &fsck{root: root, repair: repair}
+
+	// Find the enclosing show for resolving show-absolute paths.
+	if o, err := NewTeflonObject(root); err == nil {
+		f.show = o.Show
+		if c, err := o.Config(); err == nil {
+			f.format = c.MetaFormat
+		}
+	}
+	err := f.dir(root)
+	return f.problems, err
+}
+
+type fsck struct {
+	root     string
+	show     *TeflonObject
+	format   string
+	repair   bool
+	problems []FsckProblem
+}
+
+// Checks the Teflon directory of a file-system directory, then descends.
+func (f *fsck) dir(dir string) error {
+	td := filepath.Join(dir, teflonDirName)
+	if f.show == nil {
+		pm := &meta.PersistentMeta{}
+		if readMetaFile(filepath.Join(td, metaDirMetaName), pm) == nil && pm.ShowRoot {
+			f.show = &TeflonObject{Path: dir}
+			defer func() { f.show = nil }()
+		}
+	}
+	if IsDir(td) {
+		fis, err := ioutil.ReadDir(td)
+		if err != nil {
+			return err
+		}
+		for _, fi := range fis {
+			if err := f.entry(dir, fi); err != nil {
+				return err
+			}
+		}
+	}
+	if err := f.locks(filepath.Join(dir, lockDirName)); err != nil {
+		return err
+	}
+
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, fi := range fis {
+		if fi.IsDir() && fi.Name() != teflonDirName {
+			if err := f.dir(filepath.Join(dir, fi.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Checks the lock files of a lock directory.
+func (f *fsck) locks(ld string) error {
+	if !IsDir(ld) {
+		return nil
+	}
+	fis, err := ioutil.ReadDir(ld)
+	if err != nil {
+		return err
+	}
+	for _, fi := range fis {
+		fsp := filepath.Join(ld, fi.Name())
+		if strings.HasSuffix(fi.Name(), lockExtension) && time.Since(fi.ModTime()) > StaleLockAge {
+			f.report(FsckStaleLock, fsp, "stale lock file", func() error { return os.Remove(fsp) })
+		}
+	}
+	return nil
+}
+
+// Checks a single entry of a Teflon directory.
+func (f *fsck) entry(dir string, fi os.FileInfo) error {
+	n := fi.Name()
+	fsp := filepath.Join(dir, teflonDirName, n)
+	switch {
+	case fi.IsDir():
+		return nil
+
+	case strings.HasSuffix(n, lockExtension):
+		if time.Since(fi.ModTime()) > StaleLockAge {
+			f.report(FsckStaleLock, fsp, "stale lock file", func() error { return os.Remove(fsp) })
+		}
+		return nil
+
+	case strings.HasPrefix(n, ".") && strings.Contains(n, ".tmp"):
+		if time.Since(fi.ModTime()) > StaleLockAge {
+			f.report(FsckStaleTemp, fsp, "leftover temporary file", func() error { return os.Remove(fsp) })
+		}
+		return nil
+
+	case n == metaDirMetaName:
+		return f.meta(fsp, dir)
+
+	case strings.HasSuffix(n, metaExtension):
+		target := filepath.Join(dir, strings.TrimSuffix(n, metaExtension))
+		if _, err := os.Lstat(target); os.IsNotExist(err) && !seqExist(target) {
+			f.report(FsckOrphanMeta, fsp, "object doesn't exist", func() error { return os.Remove(fsp) })
+			return nil
+		}
+		return f.meta(fsp, target)
+	}
+	return nil
+}
+
+// Checks the contents of a meta file.
+func (f *fsck) meta(fsp, target string) error {
+	pm := &meta.PersistentMeta{}
+	if err := readMetaFile(fsp, pm); err != nil {
+		if _, ok := err.(*VersionError); ok {
+			return err
+		}
+		f.report(FsckCorruptMeta, fsp, "can't decode: "+err.Error(), func() error {
+			dst := fsp + ".corrupt"
+			if Exist(dst) {
+				dst += time.Now().Format(".20060102150405")
+			}
+			return os.Rename(fsp, dst)
+		})
+		return nil
+	}
+
+	if pm.ShowRoot && f.show != nil && target != f.show.Path {
+		f.report(FsckNestedShowRoot, target, "show root inside show "+f.show.Path, nil)
+	}
+
+	dangling := []string{}
+	for _, inst := range pm.Instances {
+		if !Exist(f.resolve(inst, target)) {
+			dangling = append(dangling, inst)
+		}
+	}
+	if len(dangling) == 0 {
+		return nil
+	}
+	sort.Strings(dangling)
+
+	var err error
+	if f.repair {
+		err = f.pruneInstances(fsp, dangling)
+	}
+	for _, inst := range dangling {
+		f.problems = append(f.problems, FsckProblem{
+			Code:     FsckDanglingInstance,
+			Path:     target,
+			Msg:      "instance doesn't exist: " + inst,
+			Repaired: f.repair && err == nil,
+		})
+	}
+	return err
+}
+
+// Removes Instances entries from a meta file.
+func (f *fsck) pruneInstances(fsp string, dangling []string) error {
+	l, err := LockFile(fsp)
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	pm := &meta.PersistentMeta{}
+	if err := readMetaFile(fsp, pm); err != nil {
+		return err
+	}
+	insts := []string{}
+	for _, inst := range pm.Instances {
+		if !contains(dangling, inst) {
+			insts = append(insts, inst)
+		}
+	}
+	pm.Instances = insts
+	pm.Revision++
+
+	out, err := encodeMeta(pm, f.format)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(fsp, out, 0644)
+}
+
+// Converts an Instances entry of the object at target to a file-system path.
+func (f *fsck) resolve(inst, target string) string {
+	switch {
+	case strings.HasPrefix(inst, "//"):
+		root := f.root
+		if f.show != nil {
+			root = f.show.Path
+		}
+		return filepath.Join(root, strings.TrimPrefix(inst, "//"))
+	case strings.HasPrefix(inst, "/"):
+		return filepath.Clean(inst)
+	}
+	return filepath.Join(filepath.Dir(target), inst)
+}
+
+// Records a problem and repairs it if repair is requested and possible.
+func (f *fsck) report(code, fsp, msg string, fix func() error) {
+	p := FsckProblem{Code: code, Path: fsp, Msg: msg}
+	if f.repair && fix != nil {
+		if err := fix(); err != nil {
+			p.Msg += " (repair failed: " + err.Error() + ")"
+		} else {
+			p.Repaired = true
+		}
+	}
+	f.problems = append(f.problems, p)
+}
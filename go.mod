@@ -14,4 +14,5 @@ require (
 	golang.org/x/sys v0.0.0-20190616124812-15dcb6c0061f // indirect
 	golang.org/x/text v0.3.2 // indirect
 	golang.org/x/tools v0.0.0-20190617190820-da514acc4774 // indirect
+	gopkg.in/yaml.v2 v2.2.2
 )
@@ -0,0 +1,379 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+
+	"github.com/gradient-images/teflon/internal/meta"
+)
+
+// ErrUnknownImage is returned by ReadImgInfo() for files which are not in one of
+// the supported image formats.
+var ErrUnknownImage = errors.New("Unknown image format.")
+
+// Errors of header parsing are reported as this.
+var errBadHeader = errors.New("Malformed image header.")
+
+// ReadImgInfo reads the header of an image file. The supported formats are PNG,
+// JPEG, TIFF, DPX and OpenEXR. The format is recognised by the magic number at
+// the beginning of the file, not by the extension.
+func ReadImgInfo(fspath string) (*meta.ImgInfo, error) {
+	f, err := os.Open(fspath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	magic := make([]byte, 8)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return nil, ErrUnknownImage
+	}
+
+	var ii *meta.ImgInfo
+	switch {
+	case bytes.HasPrefix(magic, []byte("\x89PNG\r\n\x1a\n")):
+		ii, err = pngInfo(f)
+	case bytes.HasPrefix(magic, []byte{0xff, 0xd8, 0xff}):
+		ii, err = jpegInfo(f)
+	case bytes.HasPrefix(magic, []byte("II*\x00")):
+		ii, err = tiffInfo(f, binary.LittleEndian)
+	case bytes.HasPrefix(magic, []byte("MM\x00*")):
+		ii, err = tiffInfo(f, binary.BigEndian)
+	case bytes.HasPrefix(magic, []byte("SDPX")):
+		ii, err = dpxInfo(f, binary.BigEndian)
+	case bytes.HasPrefix(magic, []byte("XPDS")):
+		ii, err = dpxInfo(f, binary.LittleEndian)
+	case bytes.HasPrefix(magic, []byte{0x76, 0x2f, 0x31, 0x01}):
+		ii, err = exrInfo(f)
+	default:
+		return nil, ErrUnknownImage
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = errBadHeader
+	}
+	return ii, err
+}
+
+// Probe() reads the image header of the object's file and stores the result
+// in the ImgInfo section of its metadata. Sequences are probed by their first
+// frame.
+func (o *TeflonObject) Probe() error {
+	if o.FileInfo.IsDir {
+		return ErrUnknownImage
+	}
+	fspath := o.Path
+	if o.IsSeq() {
+		fspath = o.FramePath(o.Seq.First)
+	}
+	ii, err := ReadImgInfo(fspath)
+	if err != nil {
+		return err
+	}
+	return o.UpdateMeta(func(o *TeflonObject) error {
+		o.ImgInfo = ii
+		return nil
+	})
+}
+
+// Reads a slice of a file.
+func readAt(r io.ReaderAt, off int64, n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := r.ReadAt(b, off)
+	return b, err
+}
+
+// PNG: the IHDR chunk holds the resolution and sample format, the optional pHYs
+// chunk the pixel dimensions.
+func pngInfo(r io.ReaderAt) (*meta.ImgInfo, error) {
+	ii := &meta.ImgInfo{Format: "png", PixelAspect: 1}
+	be := binary.BigEndian
+	off := int64(8)
+	for {
+		h, err := readAt(r, off, 8)
+		if err != nil {
+			return nil, err
+		}
+		n := int64(be.Uint32(h[:4]))
+		switch string(h[4:]) {
+		case "IHDR":
+			d, err := readAt(r, off+8, 13)
+			if err != nil {
+				return nil, err
+			}
+			ii.Width = int32(be.Uint32(d[0:4]))
+			ii.Height = int32(be.Uint32(d[4:8]))
+			ii.BitDepth = int32(d[8])
+			switch d[9] {
+			case 0:
+				ii.Channels = 1
+			case 2:
+				ii.Channels = 3
+			case 3:
+				// Palette entries are always 8 bit RGB.
+				ii.Channels = 3
+				ii.BitDepth = 8
+			case 4:
+				ii.Channels = 2
+			case 6:
+				ii.Channels = 4
+			}
+		case "pHYs":
+			d, err := readAt(r, off+8, 9)
+			if err != nil {
+				return nil, err
+			}
+			if x, y := be.Uint32(d[0:4]), be.Uint32(d[4:8]); x > 0 && y > 0 {
+				ii.PixelAspect = float64(y) / float64(x)
+			}
+		case "IDAT", "IEND":
+			return ii, nil
+		}
+		off += 12 + n
+	}
+}
+
+// JPEG: markers are scanned up to the start of frame, which holds the
+// resolution. The JFIF segment holds the pixel densities.
+func jpegInfo(r io.ReaderAt) (*meta.ImgInfo, error) {
+	ii := &meta.ImgInfo{Format: "jpeg", PixelAspect: 1}
+	be := binary.BigEndian
+	off := int64(2)
+	for {
+		h, err := readAt(r, off, 4)
+		if err != nil {
+			return nil, err
+		}
+		if h[0] != 0xff {
+			return nil, errBadHeader
+		}
+		m := h[1]
+		switch {
+		case m == 0xff:
+			// Fill byte.
+			off++
+			continue
+		case m == 0x01 || (m >= 0xd0 && m <= 0xd8):
+			// Markers without payload.
+			off += 2
+			continue
+		case m == 0xd9 || m == 0xda:
+			// End of image or start of scan before any frame header.
+			return nil, errBadHeader
+		}
+		l := int64(be.Uint16(h[2:4]))
+
+		switch {
+		case m == 0xe0:
+			d, err := readAt(r, off+4, 12)
+			if err == nil && bytes.HasPrefix(d, []byte("JFIF\x00")) {
+				if x, y := be.Uint16(d[8:10]), be.Uint16(d[10:12]); x > 0 && y > 0 {
+					ii.PixelAspect = float64(y) / float64(x)
+				}
+			}
+		case m >= 0xc0 && m <= 0xcf && m != 0xc4 && m != 0xc8 && m != 0xcc:
+			d, err := readAt(r, off+4, 6)
+			if err != nil {
+				return nil, err
+			}
+			ii.BitDepth = int32(d[0])
+			ii.Height = int32(be.Uint16(d[1:3]))
+			ii.Width = int32(be.Uint16(d[3:5]))
+			ii.Channels = int32(d[5])
+			return ii, nil
+		}
+		off += 2 + l
+	}
+}
+
+// TIFF: only the first image file directory is read.
+func tiffInfo(r io.ReaderAt, bo binary.ByteOrder) (*meta.ImgInfo, error) {
+	ii := &meta.ImgInfo{Format: "tiff", Channels: 1, BitDepth: 1, PixelAspect: 1}
+	h, err := readAt(r, 4, 4)
+	if err != nil {
+		return nil, err
+	}
+	ifd := int64(bo.Uint32(h))
+	h, err = readAt(r, ifd, 2)
+	if err != nil {
+		return nil, err
+	}
+	n := int(bo.Uint16(h))
+	entries, err := readAt(r, ifd+2, n*12)
+	if err != nil {
+		return nil, err
+	}
+
+	// Returns the first value of a SHORT or LONG entry.
+	value := func(e []byte) uint32 {
+		typ, count := bo.Uint16(e[2:4]), bo.Uint32(e[4:8])
+		switch {
+		case typ == 3 && count <= 2:
+			return uint32(bo.Uint16(e[8:10]))
+		case typ == 3:
+			if v, err := readAt(r, int64(bo.Uint32(e[8:12])), 2); err == nil {
+				return uint32(bo.Uint16(v))
+			}
+		case typ == 4 && count == 1:
+			return bo.Uint32(e[8:12])
+		case typ == 4:
+			if v, err := readAt(r, int64(bo.Uint32(e[8:12])), 4); err == nil {
+				return bo.Uint32(v)
+			}
+		}
+		return 0
+	}
+	// Returns the value of a RATIONAL entry.
+	rational := func(e []byte) float64 {
+		v, err := readAt(r, int64(bo.Uint32(e[8:12])), 8)
+		if err != nil || bo.Uint32(v[4:8]) == 0 {
+			return 0
+		}
+		return float64(bo.Uint32(v[0:4])) / float64(bo.Uint32(v[4:8]))
+	}
+
+	var xres, yres float64
+	for i := 0; i < n; i++ {
+		e := entries[i*12 : i*12+12]
+		switch bo.Uint16(e[0:2]) {
+		case 256:
+			ii.Width = int32(value(e))
+		case 257:
+			ii.Height = int32(value(e))
+		case 258:
+			ii.BitDepth = int32(value(e))
+		case 277:
+			ii.Channels = int32(value(e))
+		case 282:
+			xres = rational(e)
+		case 283:
+			yres = rational(e)
+		}
+	}
+	if xres > 0 && yres > 0 {
+		ii.PixelAspect = yres / xres
+	}
+	return ii, nil
+}
+
+// DPX: the generic file header has a fixed layout, only the first image element
+// is read.
+func dpxInfo(r io.ReaderAt, bo binary.ByteOrder) (*meta.ImgInfo, error) {
+	ii := &meta.ImgInfo{Format: "dpx", PixelAspect: 1}
+	h, err := readAt(r, 0, 1636)
+	if err != nil {
+		return nil, err
+	}
+	ii.Width = int32(bo.Uint32(h[772:776]))
+	ii.Height = int32(bo.Uint32(h[776:780]))
+	ii.BitDepth = int32(h[803])
+	switch h[800] {
+	case 50, 100, 102:
+		ii.Channels = 3
+	case 51, 52, 101, 103:
+		ii.Channels = 4
+	default:
+		ii.Channels = 1
+	}
+	x, y := bo.Uint32(h[1628:1632]), bo.Uint32(h[1632:1636])
+	if x > 0 && y > 0 && x != math.MaxUint32 && y != math.MaxUint32 {
+		ii.PixelAspect = float64(x) / float64(y)
+	}
+	return ii, nil
+}
+
+// OpenEXR: the header is a list of attributes, only the header of the first
+// part is read. The resolution is the size of the display window.
+func exrInfo(r io.ReaderAt) (*meta.ImgInfo, error) {
+	ii := &meta.ImgInfo{Format: "exr", PixelAspect: 1}
+	le := binary.LittleEndian
+	br := bufio.NewReader(io.NewSectionReader(r, 8, math.MaxInt32))
+
+	window := func(d []byte) *meta.Window {
+		return &meta.Window{
+			XMin: int32(le.Uint32(d[0:4])),
+			YMin: int32(le.Uint32(d[4:8])),
+			XMax: int32(le.Uint32(d[8:12])),
+			YMax: int32(le.Uint32(d[12:16])),
+		}
+	}
+
+	for {
+		name, err := br.ReadString(0)
+		if err != nil {
+			return nil, err
+		}
+		if name == "\x00" {
+			break
+		}
+		if _, err := br.ReadString(0); err != nil {
+			return nil, err
+		}
+		sb := make([]byte, 4)
+		if _, err := io.ReadFull(br, sb); err != nil {
+			return nil, err
+		}
+		size := int(int32(le.Uint32(sb)))
+		if size < 0 || size > 1<<24 {
+			return nil, errBadHeader
+		}
+		d := make([]byte, size)
+		if _, err := io.ReadFull(br, d); err != nil {
+			return nil, err
+		}
+
+		switch name[:len(name)-1] {
+		case "channels":
+			for len(d) > 1 {
+				i := bytes.IndexByte(d, 0)
+				if i < 0 || len(d) < i+17 {
+					return nil, errBadHeader
+				}
+				bits := int32(32)
+				if le.Uint32(d[i+1:i+5]) == 1 {
+					bits = 16
+				}
+				if bits > ii.BitDepth {
+					ii.BitDepth = bits
+				}
+				ii.Channels++
+				d = d[i+17:]
+			}
+		case "dataWindow":
+			if size >= 16 {
+				ii.DataWindow = window(d)
+			}
+		case "displayWindow":
+			if size >= 16 {
+				ii.DisplayWindow = window(d)
+			}
+		case "pixelAspectRatio":
+			if size >= 4 {
+				ii.PixelAspect = float64(math.Float32frombits(le.Uint32(d)))
+			}
+		}
+	}
+
+	if w := ii.DisplayWindow; w != nil {
+		ii.Width = w.XMax - w.XMin + 1
+		ii.Height = w.YMax - w.YMin + 1
+	}
+	return ii, nil
+}
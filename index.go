@@ -0,0 +1,440 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/gradient-images/teflon/internal/meta"
+
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+)
+
+// The index lives in its own directory, so writing it doesn't change the
+// modification time of the show root's Teflon directory.
+const (
+	indexDirName  = ".teflon/cache"
+	indexFileName = "index"
+)
+
+// The index of a show keeps the children of its directories, and the file info
+// and metadata of the children. A directory is trusted as long as its
+// modification time and the modification time of its Teflon directory didn't
+// change, since adding, removing or renaming files changes the former and
+// writing meta files changes the latter.
+type showIndex struct {
+	root  string
+	idx   *meta.Index
+	dirty bool
+	wrote bool
+}
+
+// indexes associates loaded indexes to show root paths. Shows without an index
+// are associated to nil.
+var indexes = map[string]*showIndex{}
+
+// mtimes caches the modification times of directories for the lifetime of the
+// process, nil meaning a missing directory. Teflon forgets the entries of the
+// directories it changes.
+var mtimes = map[string]*timestamp.Timestamp{}
+
+// IndexFile() returns the path of the index file of a show root.
+func (o *TeflonObject) IndexFile() string {
+	return filepath.Join(o.Path, indexDirName, indexFileName)
+}
+
+// Returns the index of a show, or nil if the show doesn't use an index. Only
+// sidecar files change modification times when written, so shows with other
+// meta stores are never indexed.
+func indexOf(show *TeflonObject) *showIndex {
+	if show == nil {
+		return nil
+	}
+	if si, ok := indexes[show.Path]; ok {
+		return si
+	}
+	var si *showIndex
+	if c, err := show.Config(); err == nil && c.Index && sidecarConfig(c) {
+		si = &showIndex{root: show.Path, idx: readIndex(show.IndexFile())}
+	}
+	indexes[show.Path] = si
+	return si
+}
+
+// Tells if a show config keeps metadata in sidecar files.
+func sidecarConfig(c *ShowConfig) bool {
+	_, ok := metaStores[c.MetaStore].(SidecarStore)
+	return ok
+}
+
+// Reads an index file. Missing, unreadable and outdated index files result in
+// an empty index.
+func readIndex(fspath string) *meta.Index {
+	idx := &meta.Index{}
+	if in, err := ioutil.ReadFile(fspath); err == nil {
+		if err := protobuf.Unmarshal(in, idx); err != nil {
+			log.Println("WARNING: Ignoring unreadable index:", fspath, err)
+			idx = &meta.Index{}
+		}
+	}
+	if idx.Version != MetaVersion() || idx.Dirs == nil {
+		return newIndex()
+	}
+	return idx
+}
+
+func newIndex() *meta.Index {
+	return &meta.Index{Version: MetaVersion(), Dirs: map[string]*meta.IndexDir{}}
+}
+
+// Returns the modification time of a path, cached for the process.
+func mtime(fspath string) *timestamp.Timestamp {
+	if t, ok := mtimes[fspath]; ok {
+		return t
+	}
+	var t *timestamp.Timestamp
+	if fi, err := os.Stat(fspath); err == nil {
+		t, _ = ptypes.TimestampProto(fi.ModTime())
+	}
+	mtimes[fspath] = t
+	return t
+}
+
+func sameTime(a, b *timestamp.Timestamp) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Seconds == b.Seconds && a.Nanos == b.Nanos
+}
+
+// Forgets what Teflon knows about a path in memory, after it was changed on
+// disk. The object, its directory and their Teflon directories will be read
+// from the disk again.
+func forget(fspath string) {
+	delete(Objects, fspath)
+	forgetDir(fspath)
+	forgetDir(filepath.Dir(fspath))
+}
+
+// Forgets the modification times of a directory and its Teflon directory.
+func forgetDir(fspath string) {
+	delete(mtimes, fspath)
+	delete(mtimes, filepath.Join(fspath, teflonDirName))
+}
+
+// Returns the key of a directory in the index.
+func (si *showIndex) key(fspath string) string {
+	rel, err := filepath.Rel(si.root, fspath)
+	if err != nil {
+		return fspath
+	}
+	return rel
+}
+
+// Returns the index of a directory object, rescanning it if it changed.
+func (si *showIndex) dir(o *TeflonObject) *meta.IndexDir {
+	k := si.key(o.Path)
+	mt := mtime(o.Path)
+	mmt := mtime(filepath.Join(o.Path, teflonDirName))
+	d := si.idx.Dirs[k]
+	switch {
+	case d == nil || !sameTime(d.ModTime, mt):
+		d = si.scan(o)
+	case !sameTime(d.MetaModTime, mmt):
+		si.reload(o, d)
+	default:
+		return d
+	}
+	d.ModTime, d.MetaModTime = mt, mmt
+	si.idx.Dirs[k] = d
+	si.dirty = true
+	return d
+}
+
+// Reads a directory and its children from the disk.
+func (si *showIndex) scan(o *TeflonObject) *meta.IndexDir {
+	d := &meta.IndexDir{Entries: map[string]*meta.IndexEntry{}}
+	d.Children = childrenFromDisk(o.Path)
+	for _, n := range d.Children {
+		if n == teflonDirName {
+			continue
+		}
+		if e := indexEntry(o, n); e != nil {
+			d.Entries[n] = e
+		}
+	}
+	return d
+}
+
+// Reloads the metadata of the children of a directory stored in its Teflon
+// directory.
+func (si *showIndex) reload(o *TeflonObject, d *meta.IndexDir) {
+	for n, e := range d.Entries {
+		if e.FileInfo.IsDir {
+			continue
+		}
+		c := &TeflonObject{Path: filepath.Join(o.Path, n), Show: o.Show, FileInfo: *e.FileInfo, seq: e.Seq}
+		if err := c.loadMeta(); err != nil {
+			delete(d.Entries, n)
+			continue
+		}
+		e.Meta = protobuf.Clone(&c.PersistentMeta).(*meta.PersistentMeta)
+	}
+}
+
+// Reads a child of a directory object from the disk. Returns nil if the child
+// can't be read, its errors are reported when it's read without the index.
+func indexEntry(o *TeflonObject, name string) *meta.IndexEntry {
+	c := &TeflonObject{Path: filepath.Join(o.Path, name), Show: o.Show}
+	if err := c.initFromDisk(); err != nil {
+		return nil
+	}
+	e := &meta.IndexEntry{
+		FileInfo: protobuf.Clone(&c.FileInfo).(*meta.FileInfo),
+		Meta:     protobuf.Clone(&c.PersistentMeta).(*meta.PersistentMeta),
+		Seq:      c.seq,
+	}
+	if c.FileInfo.IsDir {
+		e.FileInfo.ModTime = mtime(c.Path)
+		e.MetaModTime = mtime(filepath.Join(c.Path, teflonDirName))
+	}
+	return e
+}
+
+// Initializes the object from the index of its show. Tells if the object was
+// found in the index.
+func (o *TeflonObject) loadIndexed() bool {
+	if o.Parent == nil {
+		return false
+	}
+	si := indexOf(o.Parent.Show)
+	if si == nil {
+		return false
+	}
+	d := si.dir(o.Parent)
+	name := filepath.Base(o.Path)
+	e := d.Entries[name]
+	if e == nil {
+		return false
+	}
+
+	// Directories keep their own metadata, so it's checked separately. Files
+	// can be rewritten in place without changing their directory, so their
+	// file info is checked on every lookup.
+	if e.FileInfo.IsDir && (!sameTime(e.FileInfo.ModTime, mtime(o.Path)) ||
+		!sameTime(e.MetaModTime, mtime(filepath.Join(o.Path, teflonDirName)))) ||
+		!e.FileInfo.IsDir && !e.Seq && fileChanged(o.Path, e.FileInfo) {
+		if e = indexEntry(o.Parent, name); e == nil {
+			delete(d.Entries, name)
+			return false
+		}
+		d.Entries[name] = e
+		si.dirty = true
+	}
+
+	o.FileInfo = *protobuf.Clone(e.FileInfo).(*meta.FileInfo)
+	o.PersistentMeta = *protobuf.Clone(e.Meta).(*meta.PersistentMeta)
+	o.seq = e.Seq
+	if o.UserData == nil {
+		o.UserData = make(map[string]string)
+	}
+	o.base = protobuf.Clone(&o.PersistentMeta).(*meta.PersistentMeta)
+	return true
+}
+
+// Tells if the size, mode or modification time of a file differs from its
+// indexed file info.
+func fileChanged(fspath string, fi *meta.FileInfo) bool {
+	st, err := os.Stat(fspath)
+	if err != nil {
+		return true
+	}
+	mt, _ := ptypes.TimestampProto(st.ModTime())
+	return st.Size() != fi.Size || uint32(st.Mode()) != fi.Mode || !sameTime(mt, fi.ModTime)
+}
+
+// Returns the children names of a directory object from the index of its show.
+// Tells if the show has an index.
+func (o *TeflonObject) indexedChildren() ([]string, bool) {
+	si := indexOf(o.Show)
+	if si == nil {
+		return nil, false
+	}
+	return append([]string{}, si.dir(o).Children...), true
+}
+
+// Updates the index after the metadata of the object was written. The write
+// changed the modification times of the Teflon directories holding the meta
+// file and the journal, so their metadata is reloaded on the next lookup.
+func (o *TeflonObject) updateIndexed() {
+	if o.Parent == nil {
+		return
+	}
+	si := indexOf(o.Parent.Show)
+	if si == nil {
+		return
+	}
+	d := si.idx.Dirs[si.key(o.Parent.Path)]
+	if d == nil || d.Entries[filepath.Base(o.Path)] == nil {
+		return
+	}
+	if !si.wrote {
+		// A crashed process must not leave a stale index behind, so the index
+		// file is removed until the index is saved again.
+		os.Remove(filepath.Join(si.root, indexDirName, indexFileName))
+		si.wrote = true
+	}
+	e := d.Entries[filepath.Base(o.Path)]
+	e.Meta = protobuf.Clone(&o.PersistentMeta).(*meta.PersistentMeta)
+
+	if o.FileInfo.IsDir {
+		si.touch(o.Path)
+		e.FileInfo.ModTime = mtime(o.Path)
+		e.MetaModTime = nil
+	} else {
+		si.touch(o.Parent.Path)
+	}
+	si.touch(si.root)
+	si.dirty = true
+}
+
+// Re-reads the modification time of an indexed directory after Teflon has
+// written a meta file in it. If the children of the directory changed
+// meanwhile, the directory is dropped from the index. Other processes may have
+// written meta files in the directory too, so its metadata is marked for
+// reloading instead of trusting the new modification time of its Teflon
+// directory.
+func (si *showIndex) touch(fspath string) {
+	k := si.key(fspath)
+	forgetDir(fspath)
+	d := si.idx.Dirs[k]
+	if d == nil {
+		return
+	}
+	if mt := mtime(fspath); !sameTime(d.ModTime, mt) {
+		ch := childrenFromDisk(fspath)
+		if !sameNames(ch, d.Children) && !sameNames(ch, append(d.Children, teflonDirName)) {
+			delete(si.idx.Dirs, k)
+			return
+		}
+		d.Children, d.ModTime = ch, mt
+	}
+	d.MetaModTime = nil
+}
+
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = append([]string{}, a...), append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// SaveIndexes() writes the indexes changed by the process to disk. The teflon
+// command calls it before exiting.
+func SaveIndexes() error {
+	for _, si := range indexes {
+		if si == nil || !si.dirty {
+			continue
+		}
+		dir := filepath.Join(si.root, indexDirName)
+		if !Exist(dir) {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+			si.touch(si.root)
+		}
+		out, err := protobuf.Marshal(si.idx)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(filepath.Join(dir, indexFileName), out, 0644); err != nil {
+			return err
+		}
+		si.dirty = false
+	}
+	return nil
+}
+
+// BuildIndex() turns on the index of the show and fills it by reading the whole
+// show. The object has to be a show root. It returns the number of indexed
+// directories.
+func (o *TeflonObject) BuildIndex() (int, error) {
+	if !o.ShowRoot {
+		return 0, errors.New("Not a show root: " + o.Path)
+	}
+	c, err := o.Config()
+	if err != nil {
+		return 0, err
+	}
+	if !sidecarConfig(c) {
+		return 0, errors.New("Only shows with sidecar meta files can be indexed: " + o.Path)
+	}
+	if !c.Index {
+		c.Index = true
+		if err := o.SaveConfig(c); err != nil {
+			return 0, err
+		}
+	}
+	delete(indexes, o.Path)
+	si := indexOf(o)
+	si.idx = newIndex()
+	si.dirty = true
+
+	count := 0
+	err = o.Walk(func(d *TeflonObject) error {
+		if d.FileInfo.IsDir && d.Show == o {
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, SaveIndexes()
+}
+
+// DropIndex() turns off the index of the show and removes the index file. The
+// object has to be a show root.
+func (o *TeflonObject) DropIndex() error {
+	if !o.ShowRoot {
+		return errors.New("Not a show root: " + o.Path)
+	}
+	c, err := o.Config()
+	if err != nil {
+		return err
+	}
+	c.Index = false
+	if err := o.SaveConfig(c); err != nil {
+		return err
+	}
+	indexes[o.Path] = nil
+	err = os.RemoveAll(filepath.Join(o.Path, indexDirName))
+	forgetDir(o.Path)
+	return err
+}
@@ -0,0 +1,91 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+// MetaOrigin is an inherited user metadata value together with the path of the
+// object it was found on.
+type MetaOrigin struct {
+	Value  string
+	Origin string
+}
+
+// LookupMeta finds the value of a user metadata entry. If the object doesn't
+// have the key itself, the value comes from the nearest ancestor up to the show
+// root. Ancestors listing the key in their NoInherit list are skipped, while an
+// object listing the key in its Unset list stops the search. Objects outside of
+// shows don't inherit anything.
+func (o *TeflonObject) LookupMeta(key string) (value string, origin *TeflonObject, ok bool) {
+	if v, ok := o.UserData[key]; ok {
+		return v, o, true
+	}
+	if o.Show == nil {
+		return "", nil, false
+	}
+	for p := o; p != nil; p = p.Parent {
+		if contains(p.Unset, key) {
+			return "", nil, false
+		}
+		if p != o && !contains(p.NoInherit, key) {
+			if v, ok := p.UserData[key]; ok {
+				return v, p, true
+			}
+		}
+		if p.ShowRoot {
+			break
+		}
+	}
+	return "", nil, false
+}
+
+// InheritedMeta returns all the user metadata entries visible on the object,
+// including the inherited ones, with their origins.
+func (o *TeflonObject) InheritedMeta() map[string]MetaOrigin {
+	im := map[string]MetaOrigin{}
+	chain := []*TeflonObject{o}
+	if o.Show != nil {
+		for p := o; !p.ShowRoot && p.Parent != nil; p = p.Parent {
+			chain = append(chain, p.Parent)
+		}
+	}
+	for _, p := range chain {
+		for k := range p.UserData {
+			if _, ok := im[k]; ok {
+				continue
+			}
+			if v, src, ok := o.LookupMeta(k); ok {
+				im[k] = MetaOrigin{Value: v, Origin: src.Path}
+			}
+		}
+	}
+	return im
+}
+
+// UnsetMeta deletes an entry from the user section of the metadata and stops
+// the inheritance of the key from the ancestors.
+func (o *TeflonObject) UnsetMeta(key string) {
+	delete(o.UserData, key)
+	if !contains(o.Unset, key) {
+		o.Unset = append(o.Unset, key)
+	}
+}
+
+// SetInherit controls whether the object's value of a key is inherited by its
+// descendants.
+func (o *TeflonObject) SetInherit(key string, inherit bool) {
+	if inherit {
+		o.NoInherit = remove(o.NoInherit, key)
+	} else if !contains(o.NoInherit, key) {
+		o.NoInherit = append(o.NoInherit, key)
+	}
+}
@@ -14,14 +14,12 @@
 package teflon
 
 import (
-	"log"
 	"os"
 )
 
 func init() {
+	// The command line checks that TEFLONCONF is set, so the package can be
+	// tested without it.
 	TeflonConf = os.Getenv("TEFLONCONF")
-	if TeflonConf == "" {
-		log.Fatalln("FATAL: TEFLONCONF environment variable is not set.")
-	}
 	go listen()
 }
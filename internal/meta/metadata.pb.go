@@ -209,6 +209,13 @@ type PersistentMeta struct {
 	UserData             map[string]string `protobuf:"bytes,4,rep,name=UserData,proto3" json:"UserData,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 	ImgInfo              *ImgInfo          `protobuf:"bytes,5,opt,name=ImgInfo,proto3" json:"ImgInfo,omitempty"`
 	Seq                  *Seq              `protobuf:"bytes,6,opt,name=Seq,proto3" json:"Seq,omitempty"`
+	Unset                []string          `protobuf:"bytes,7,rep,name=Unset,proto3" json:"Unset,omitempty"`
+	NoInherit            []string          `protobuf:"bytes,8,rep,name=NoInherit,proto3" json:"NoInherit,omitempty"`
+	ShowProto            string            `protobuf:"bytes,9,opt,name=ShowProto,proto3" json:"ShowProto,omitempty"`
+	Revision             uint64            `protobuf:"varint,10,opt,name=Revision,proto3" json:"Revision,omitempty"`
+	Version              uint32            `protobuf:"varint,11,opt,name=Version,proto3" json:"Version,omitempty"`
+	Checksum             *Checksum         `protobuf:"bytes,12,opt,name=Checksum,proto3" json:"Checksum,omitempty"`
+	Verification         *Verification     `protobuf:"bytes,13,opt,name=Verification,proto3" json:"Verification,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
 	XXX_unrecognized     []byte            `json:"-"`
 	XXX_sizecache        int32             `json:"-"`
@@ -281,8 +288,58 @@ func (m *PersistentMeta) GetSeq() *Seq {
 	return nil
 }
 
+func (m *PersistentMeta) GetUnset() []string {
+	if m != nil {
+		return m.Unset
+	}
+	return nil
+}
+
+func (m *PersistentMeta) GetNoInherit() []string {
+	if m != nil {
+		return m.NoInherit
+	}
+	return nil
+}
+
+func (m *PersistentMeta) GetShowProto() string {
+	if m != nil {
+		return m.ShowProto
+	}
+	return ""
+}
+
+func (m *PersistentMeta) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *PersistentMeta) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *PersistentMeta) GetChecksum() *Checksum {
+	if m != nil {
+		return m.Checksum
+	}
+	return nil
+}
+
+func (m *PersistentMeta) GetVerification() *Verification {
+	if m != nil {
+		return m.Verification
+	}
+	return nil
+}
+
 type Contract struct {
 	Pattern              string   `protobuf:"bytes,1,opt,name=Pattern,proto3" json:"Pattern,omitempty"`
+	Dirs                 []string `protobuf:"bytes,2,rep,name=Dirs,proto3" json:"Dirs,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -320,9 +377,22 @@ func (m *Contract) GetPattern() string {
 	return ""
 }
 
+func (m *Contract) GetDirs() []string {
+	if m != nil {
+		return m.Dirs
+	}
+	return nil
+}
+
 type ImgInfo struct {
 	Width                int32    `protobuf:"varint,1,opt,name=Width,proto3" json:"Width,omitempty"`
 	Height               int32    `protobuf:"varint,2,opt,name=Height,proto3" json:"Height,omitempty"`
+	Format               string   `protobuf:"bytes,3,opt,name=Format,proto3" json:"Format,omitempty"`
+	Channels             int32    `protobuf:"varint,4,opt,name=Channels,proto3" json:"Channels,omitempty"`
+	BitDepth             int32    `protobuf:"varint,5,opt,name=BitDepth,proto3" json:"BitDepth,omitempty"`
+	PixelAspect          float64  `protobuf:"fixed64,6,opt,name=PixelAspect,proto3" json:"PixelAspect,omitempty"`
+	DataWindow           *Window  `protobuf:"bytes,7,opt,name=DataWindow,proto3" json:"DataWindow,omitempty"`
+	DisplayWindow        *Window  `protobuf:"bytes,8,opt,name=DisplayWindow,proto3" json:"DisplayWindow,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -367,10 +437,244 @@ func (m *ImgInfo) GetHeight() int32 {
 	return 0
 }
 
+func (m *ImgInfo) GetFormat() string {
+	if m != nil {
+		return m.Format
+	}
+	return ""
+}
+
+func (m *ImgInfo) GetChannels() int32 {
+	if m != nil {
+		return m.Channels
+	}
+	return 0
+}
+
+func (m *ImgInfo) GetBitDepth() int32 {
+	if m != nil {
+		return m.BitDepth
+	}
+	return 0
+}
+
+func (m *ImgInfo) GetPixelAspect() float64 {
+	if m != nil {
+		return m.PixelAspect
+	}
+	return 0
+}
+
+func (m *ImgInfo) GetDataWindow() *Window {
+	if m != nil {
+		return m.DataWindow
+	}
+	return nil
+}
+
+func (m *ImgInfo) GetDisplayWindow() *Window {
+	if m != nil {
+		return m.DisplayWindow
+	}
+	return nil
+}
+
+type Window struct {
+	XMin                 int32    `protobuf:"varint,1,opt,name=XMin,proto3" json:"XMin,omitempty"`
+	YMin                 int32    `protobuf:"varint,2,opt,name=YMin,proto3" json:"YMin,omitempty"`
+	XMax                 int32    `protobuf:"varint,3,opt,name=XMax,proto3" json:"XMax,omitempty"`
+	YMax                 int32    `protobuf:"varint,4,opt,name=YMax,proto3" json:"YMax,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Window) Reset()         { *m = Window{} }
+func (m *Window) String() string { return proto.CompactTextString(m) }
+func (*Window) ProtoMessage()    {}
+func (*Window) Descriptor() ([]byte, []int) {
+	return fileDescriptor_56d9f74966f40d04, []int{6}
+}
+
+func (m *Window) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Window.Unmarshal(m, b)
+}
+func (m *Window) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Window.Marshal(b, m, deterministic)
+}
+func (m *Window) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Window.Merge(m, src)
+}
+func (m *Window) XXX_Size() int {
+	return xxx_messageInfo_Window.Size(m)
+}
+func (m *Window) XXX_DiscardUnknown() {
+	xxx_messageInfo_Window.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Window proto.InternalMessageInfo
+
+func (m *Window) GetXMin() int32 {
+	if m != nil {
+		return m.XMin
+	}
+	return 0
+}
+
+func (m *Window) GetYMin() int32 {
+	if m != nil {
+		return m.YMin
+	}
+	return 0
+}
+
+func (m *Window) GetXMax() int32 {
+	if m != nil {
+		return m.XMax
+	}
+	return 0
+}
+
+func (m *Window) GetYMax() int32 {
+	if m != nil {
+		return m.YMax
+	}
+	return 0
+}
+
+type Checksum struct {
+	SHA256               string               `protobuf:"bytes,1,opt,name=SHA256,proto3" json:"SHA256,omitempty"`
+	XXH64                string               `protobuf:"bytes,2,opt,name=XXH64,proto3" json:"XXH64,omitempty"`
+	Size                 int64                `protobuf:"varint,3,opt,name=Size,proto3" json:"Size,omitempty"`
+	ModTime              *timestamp.Timestamp `protobuf:"bytes,4,opt,name=ModTime,proto3" json:"ModTime,omitempty"`
+	Time                 *timestamp.Timestamp `protobuf:"bytes,5,opt,name=Time,proto3" json:"Time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Checksum) Reset()         { *m = Checksum{} }
+func (m *Checksum) String() string { return proto.CompactTextString(m) }
+func (*Checksum) ProtoMessage()    {}
+func (*Checksum) Descriptor() ([]byte, []int) {
+	return fileDescriptor_56d9f74966f40d04, []int{7}
+}
+
+func (m *Checksum) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Checksum.Unmarshal(m, b)
+}
+func (m *Checksum) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Checksum.Marshal(b, m, deterministic)
+}
+func (m *Checksum) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Checksum.Merge(m, src)
+}
+func (m *Checksum) XXX_Size() int {
+	return xxx_messageInfo_Checksum.Size(m)
+}
+func (m *Checksum) XXX_DiscardUnknown() {
+	xxx_messageInfo_Checksum.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Checksum proto.InternalMessageInfo
+
+func (m *Checksum) GetSHA256() string {
+	if m != nil {
+		return m.SHA256
+	}
+	return ""
+}
+
+func (m *Checksum) GetXXH64() string {
+	if m != nil {
+		return m.XXH64
+	}
+	return ""
+}
+
+func (m *Checksum) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *Checksum) GetModTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.ModTime
+	}
+	return nil
+}
+
+func (m *Checksum) GetTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.Time
+	}
+	return nil
+}
+
+type Verification struct {
+	Manifest             string               `protobuf:"bytes,1,opt,name=Manifest,proto3" json:"Manifest,omitempty"`
+	Status               string               `protobuf:"bytes,2,opt,name=Status,proto3" json:"Status,omitempty"`
+	Time                 *timestamp.Timestamp `protobuf:"bytes,3,opt,name=Time,proto3" json:"Time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Verification) Reset()         { *m = Verification{} }
+func (m *Verification) String() string { return proto.CompactTextString(m) }
+func (*Verification) ProtoMessage()    {}
+func (*Verification) Descriptor() ([]byte, []int) {
+	return fileDescriptor_56d9f74966f40d04, []int{8}
+}
+
+func (m *Verification) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Verification.Unmarshal(m, b)
+}
+func (m *Verification) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Verification.Marshal(b, m, deterministic)
+}
+func (m *Verification) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Verification.Merge(m, src)
+}
+func (m *Verification) XXX_Size() int {
+	return xxx_messageInfo_Verification.Size(m)
+}
+func (m *Verification) XXX_DiscardUnknown() {
+	xxx_messageInfo_Verification.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Verification proto.InternalMessageInfo
+
+func (m *Verification) GetManifest() string {
+	if m != nil {
+		return m.Manifest
+	}
+	return ""
+}
+
+func (m *Verification) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *Verification) GetTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.Time
+	}
+	return nil
+}
+
 type Seq struct {
 	BaseName             string   `protobuf:"bytes,1,opt,name=BaseName,proto3" json:"BaseName,omitempty"`
 	First                int32    `protobuf:"varint,2,opt,name=First,proto3" json:"First,omitempty"`
 	Last                 int32    `protobuf:"varint,3,opt,name=Last,proto3" json:"Last,omitempty"`
+	Padding              int32    `protobuf:"varint,4,opt,name=Padding,proto3" json:"Padding,omitempty"`
+	Extension            string   `protobuf:"bytes,5,opt,name=Extension,proto3" json:"Extension,omitempty"`
+	Missing              []int32  `protobuf:"varint,6,rep,packed,name=Missing,proto3" json:"Missing,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -380,7 +684,7 @@ func (m *Seq) Reset()         { *m = Seq{} }
 func (m *Seq) String() string { return proto.CompactTextString(m) }
 func (*Seq) ProtoMessage()    {}
 func (*Seq) Descriptor() ([]byte, []int) {
-	return fileDescriptor_56d9f74966f40d04, []int{6}
+	return fileDescriptor_56d9f74966f40d04, []int{9}
 }
 
 func (m *Seq) XXX_Unmarshal(b []byte) error {
@@ -422,6 +726,200 @@ func (m *Seq) GetLast() int32 {
 	return 0
 }
 
+func (m *Seq) GetPadding() int32 {
+	if m != nil {
+		return m.Padding
+	}
+	return 0
+}
+
+func (m *Seq) GetExtension() string {
+	if m != nil {
+		return m.Extension
+	}
+	return ""
+}
+
+func (m *Seq) GetMissing() []int32 {
+	if m != nil {
+		return m.Missing
+	}
+	return nil
+}
+
+type Index struct {
+	Version              uint32               `protobuf:"varint,1,opt,name=Version,proto3" json:"Version,omitempty"`
+	Dirs                 map[string]*IndexDir `protobuf:"bytes,2,rep,name=Dirs,proto3" json:"Dirs,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Index) Reset()         { *m = Index{} }
+func (m *Index) String() string { return proto.CompactTextString(m) }
+func (*Index) ProtoMessage()    {}
+func (*Index) Descriptor() ([]byte, []int) {
+	return fileDescriptor_56d9f74966f40d04, []int{10}
+}
+
+func (m *Index) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Index.Unmarshal(m, b)
+}
+func (m *Index) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Index.Marshal(b, m, deterministic)
+}
+func (m *Index) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Index.Merge(m, src)
+}
+func (m *Index) XXX_Size() int {
+	return xxx_messageInfo_Index.Size(m)
+}
+func (m *Index) XXX_DiscardUnknown() {
+	xxx_messageInfo_Index.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Index proto.InternalMessageInfo
+
+func (m *Index) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *Index) GetDirs() map[string]*IndexDir {
+	if m != nil {
+		return m.Dirs
+	}
+	return nil
+}
+
+type IndexDir struct {
+	ModTime              *timestamp.Timestamp   `protobuf:"bytes,1,opt,name=ModTime,proto3" json:"ModTime,omitempty"`
+	MetaModTime          *timestamp.Timestamp   `protobuf:"bytes,2,opt,name=MetaModTime,proto3" json:"MetaModTime,omitempty"`
+	Children             []string               `protobuf:"bytes,3,rep,name=Children,proto3" json:"Children,omitempty"`
+	Entries              map[string]*IndexEntry `protobuf:"bytes,4,rep,name=Entries,proto3" json:"Entries,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
+}
+
+func (m *IndexDir) Reset()         { *m = IndexDir{} }
+func (m *IndexDir) String() string { return proto.CompactTextString(m) }
+func (*IndexDir) ProtoMessage()    {}
+func (*IndexDir) Descriptor() ([]byte, []int) {
+	return fileDescriptor_56d9f74966f40d04, []int{11}
+}
+
+func (m *IndexDir) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IndexDir.Unmarshal(m, b)
+}
+func (m *IndexDir) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IndexDir.Marshal(b, m, deterministic)
+}
+func (m *IndexDir) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IndexDir.Merge(m, src)
+}
+func (m *IndexDir) XXX_Size() int {
+	return xxx_messageInfo_IndexDir.Size(m)
+}
+func (m *IndexDir) XXX_DiscardUnknown() {
+	xxx_messageInfo_IndexDir.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IndexDir proto.InternalMessageInfo
+
+func (m *IndexDir) GetModTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.ModTime
+	}
+	return nil
+}
+
+func (m *IndexDir) GetMetaModTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.MetaModTime
+	}
+	return nil
+}
+
+func (m *IndexDir) GetChildren() []string {
+	if m != nil {
+		return m.Children
+	}
+	return nil
+}
+
+func (m *IndexDir) GetEntries() map[string]*IndexEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+type IndexEntry struct {
+	FileInfo             *FileInfo            `protobuf:"bytes,1,opt,name=FileInfo,proto3" json:"FileInfo,omitempty"`
+	Meta                 *PersistentMeta      `protobuf:"bytes,2,opt,name=Meta,proto3" json:"Meta,omitempty"`
+	Seq                  bool                 `protobuf:"varint,3,opt,name=Seq,proto3" json:"Seq,omitempty"`
+	MetaModTime          *timestamp.Timestamp `protobuf:"bytes,4,opt,name=MetaModTime,proto3" json:"MetaModTime,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *IndexEntry) Reset()         { *m = IndexEntry{} }
+func (m *IndexEntry) String() string { return proto.CompactTextString(m) }
+func (*IndexEntry) ProtoMessage()    {}
+func (*IndexEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_56d9f74966f40d04, []int{12}
+}
+
+func (m *IndexEntry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IndexEntry.Unmarshal(m, b)
+}
+func (m *IndexEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IndexEntry.Marshal(b, m, deterministic)
+}
+func (m *IndexEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IndexEntry.Merge(m, src)
+}
+func (m *IndexEntry) XXX_Size() int {
+	return xxx_messageInfo_IndexEntry.Size(m)
+}
+func (m *IndexEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_IndexEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IndexEntry proto.InternalMessageInfo
+
+func (m *IndexEntry) GetFileInfo() *FileInfo {
+	if m != nil {
+		return m.FileInfo
+	}
+	return nil
+}
+
+func (m *IndexEntry) GetMeta() *PersistentMeta {
+	if m != nil {
+		return m.Meta
+	}
+	return nil
+}
+
+func (m *IndexEntry) GetSeq() bool {
+	if m != nil {
+		return m.Seq
+	}
+	return false
+}
+
+func (m *IndexEntry) GetMetaModTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.MetaModTime
+	}
+	return nil
+}
+
 type UserArray struct {
 	A                    []*UserValue `protobuf:"bytes,1,rep,name=A,proto3" json:"A,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
@@ -433,7 +931,7 @@ func (m *UserArray) Reset()         { *m = UserArray{} }
 func (m *UserArray) String() string { return proto.CompactTextString(m) }
 func (*UserArray) ProtoMessage()    {}
 func (*UserArray) Descriptor() ([]byte, []int) {
-	return fileDescriptor_56d9f74966f40d04, []int{7}
+	return fileDescriptor_56d9f74966f40d04, []int{13}
 }
 
 func (m *UserArray) XXX_Unmarshal(b []byte) error {
@@ -472,7 +970,7 @@ func (m *UserObject) Reset()         { *m = UserObject{} }
 func (m *UserObject) String() string { return proto.CompactTextString(m) }
 func (*UserObject) ProtoMessage()    {}
 func (*UserObject) Descriptor() ([]byte, []int) {
-	return fileDescriptor_56d9f74966f40d04, []int{8}
+	return fileDescriptor_56d9f74966f40d04, []int{14}
 }
 
 func (m *UserObject) XXX_Unmarshal(b []byte) error {
@@ -517,7 +1015,7 @@ func (m *UserValue) Reset()         { *m = UserValue{} }
 func (m *UserValue) String() string { return proto.CompactTextString(m) }
 func (*UserValue) ProtoMessage()    {}
 func (*UserValue) Descriptor() ([]byte, []int) {
-	return fileDescriptor_56d9f74966f40d04, []int{9}
+	return fileDescriptor_56d9f74966f40d04, []int{15}
 }
 
 func (m *UserValue) XXX_Unmarshal(b []byte) error {
@@ -633,7 +1131,15 @@ func init() {
 	proto.RegisterMapType((map[string]string)(nil), "meta.PersistentMeta.UserDataEntry")
 	proto.RegisterType((*Contract)(nil), "meta.Contract")
 	proto.RegisterType((*ImgInfo)(nil), "meta.ImgInfo")
+	proto.RegisterType((*Window)(nil), "meta.Window")
+	proto.RegisterType((*Checksum)(nil), "meta.Checksum")
+	proto.RegisterType((*Verification)(nil), "meta.Verification")
 	proto.RegisterType((*Seq)(nil), "meta.Seq")
+	proto.RegisterType((*Index)(nil), "meta.Index")
+	proto.RegisterMapType((map[string]*IndexDir)(nil), "meta.Index.DirsEntry")
+	proto.RegisterType((*IndexDir)(nil), "meta.IndexDir")
+	proto.RegisterMapType((map[string]*IndexEntry)(nil), "meta.IndexDir.EntriesEntry")
+	proto.RegisterType((*IndexEntry)(nil), "meta.IndexEntry")
 	proto.RegisterType((*UserArray)(nil), "meta.UserArray")
 	proto.RegisterType((*UserObject)(nil), "meta.UserObject")
 	proto.RegisterMapType((map[string]*UserValue)(nil), "meta.UserObject.OEntry")
@@ -643,45 +1149,77 @@ func init() {
 func init() { proto.RegisterFile("metadata.proto", fileDescriptor_56d9f74966f40d04) }
 
 var fileDescriptor_56d9f74966f40d04 = []byte{
-	// 631 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x53, 0x6f, 0x4f, 0x13, 0x4f,
-	0x10, 0xfe, 0x2d, 0xd7, 0x6b, 0x7b, 0xc3, 0x8f, 0x4a, 0x36, 0x44, 0x2f, 0x55, 0x43, 0x73, 0x91,
-	0xd8, 0xf0, 0xe2, 0x48, 0x90, 0x44, 0xa3, 0x89, 0x49, 0x2b, 0x10, 0x1a, 0x85, 0x36, 0x5b, 0x84,
-	0xd7, 0x0b, 0x5d, 0xca, 0x69, 0xef, 0x0e, 0xf6, 0x16, 0x0d, 0x7c, 0x01, 0xdf, 0xfb, 0x6d, 0xfc,
-	0x76, 0x66, 0x66, 0x6f, 0xaf, 0x85, 0xe0, 0xbb, 0x79, 0xe6, 0xdf, 0x33, 0xf3, 0xec, 0x2c, 0xb4,
-	0x52, 0x65, 0xe4, 0x44, 0x1a, 0x19, 0x5f, 0xe9, 0xdc, 0xe4, 0xbc, 0x86, 0xb8, 0xbd, 0x3e, 0xcd,
-	0xf3, 0xe9, 0x4c, 0x6d, 0x91, 0xef, 0xec, 0xe6, 0x62, 0xcb, 0x24, 0xa9, 0x2a, 0x8c, 0x4c, 0xaf,
-	0x6c, 0x5a, 0xa4, 0x01, 0x4e, 0x13, 0xad, 0x86, 0x67, 0xdf, 0xd4, 0xb9, 0xe1, 0x31, 0x34, 0x4f,
-	0xf2, 0x99, 0x34, 0xc9, 0x4c, 0x85, 0xac, 0xc3, 0xba, 0xcb, 0xdb, 0x3c, 0xc6, 0x3e, 0xb1, 0xf3,
-	0x1e, 0x2a, 0x23, 0x45, 0x95, 0xc3, 0x77, 0x00, 0x46, 0x4a, 0x17, 0x49, 0x61, 0x54, 0x66, 0xc2,
-	0x25, 0xaa, 0x58, 0xb3, 0x15, 0x73, 0x3f, 0xd5, 0x2c, 0xe4, 0x45, 0x77, 0xf0, 0xff, 0x62, 0x3f,
-	0xce, 0xa1, 0x36, 0x92, 0xe6, 0x92, 0x18, 0x03, 0x41, 0x36, 0xfa, 0xc6, 0x97, 0xf9, 0x4f, 0xea,
-	0x19, 0x08, 0xb2, 0xf9, 0x26, 0x34, 0xf7, 0x93, 0x99, 0x1a, 0x64, 0x17, 0x79, 0xe8, 0x11, 0x57,
-	0xcb, 0x72, 0x39, 0xaf, 0xa8, 0xe2, 0xfc, 0x29, 0xd4, 0x47, 0x52, 0xe3, 0x54, 0x35, 0xea, 0x50,
-	0xa2, 0xe8, 0x37, 0x9b, 0x37, 0x41, 0x92, 0x23, 0x99, 0x2a, 0x47, 0x8c, 0x36, 0x11, 0x27, 0x77,
-	0x8a, 0x88, 0x3d, 0x41, 0x36, 0xfa, 0x0e, 0xf3, 0x89, 0x22, 0xd2, 0x15, 0x41, 0x36, 0xdf, 0x81,
-	0xc6, 0x61, 0x3e, 0x39, 0x4e, 0x52, 0x45, 0x0c, 0xcb, 0xdb, 0xed, 0xd8, 0x6a, 0x1d, 0x3b, 0xad,
-	0xe3, 0x63, 0xa7, 0xb5, 0x70, 0xa9, 0x7c, 0x0d, 0xfc, 0x41, 0xb1, 0x9b, 0xe8, 0xd0, 0xef, 0xb0,
-	0x6e, 0x53, 0x58, 0x10, 0xfd, 0x59, 0x82, 0xd6, 0x7d, 0xbd, 0x78, 0x1b, 0x9a, 0xb8, 0xb3, 0xc8,
-	0x73, 0x43, 0xe3, 0x35, 0x45, 0x85, 0x51, 0x87, 0x4f, 0x79, 0x66, 0xb4, 0x3c, 0x77, 0x9a, 0x97,
-	0x3a, 0x38, 0xaf, 0xa8, 0xe2, 0xfc, 0x05, 0x04, 0x83, 0xac, 0x30, 0x32, 0x3b, 0x57, 0x45, 0xe8,
-	0x75, 0xbc, 0x6e, 0x20, 0xe6, 0x0e, 0xfe, 0x11, 0x9a, 0x5f, 0x0b, 0xa5, 0x77, 0xa5, 0x91, 0x61,
-	0xad, 0xe3, 0x75, 0x97, 0xb7, 0xa3, 0xc7, 0x5e, 0x2f, 0x76, 0x49, 0x7b, 0x99, 0xd1, 0xb7, 0xa2,
-	0xaa, 0xe1, 0xaf, 0xa1, 0x31, 0x48, 0xa7, 0xf4, 0x20, 0x3e, 0x0d, 0xb2, 0x62, 0xcb, 0x4b, 0xa7,
-	0x70, 0x51, 0xfe, 0x1c, 0xbc, 0xb1, 0xba, 0x0e, 0xeb, 0x94, 0x14, 0xd8, 0xa4, 0xb1, 0xba, 0x16,
-	0xe8, 0x6d, 0x7f, 0x80, 0x95, 0x7b, 0x04, 0x7c, 0x15, 0xbc, 0xef, 0xea, 0xb6, 0x7c, 0x16, 0x34,
-	0x51, 0xb7, 0x1f, 0x72, 0x76, 0xa3, 0xca, 0x7b, 0xb0, 0xe0, 0xfd, 0xd2, 0x3b, 0x16, 0xbd, 0x9a,
-	0x8b, 0xc1, 0x43, 0x68, 0x8c, 0xa4, 0x31, 0x4a, 0x67, 0x65, 0xad, 0x83, 0xd1, 0xdb, 0x6a, 0x50,
-	0x6c, 0x75, 0x9a, 0x4c, 0xca, 0x73, 0xf3, 0x85, 0x05, 0x78, 0x2f, 0x07, 0x2a, 0x99, 0x5e, 0x5a,
-	0x45, 0x7d, 0x51, 0xa2, 0xe8, 0x33, 0x0d, 0x8e, 0xcf, 0xd1, 0x97, 0x85, 0x5a, 0xb8, 0x96, 0x0a,
-	0x63, 0xc3, 0xfd, 0x44, 0x17, 0xae, 0xd2, 0x02, 0xbc, 0x99, 0x2f, 0xb2, 0x30, 0x74, 0x33, 0xbe,
-	0x20, 0x3b, 0xda, 0x84, 0x00, 0x17, 0xed, 0x69, 0x2d, 0x6f, 0xf9, 0x4b, 0x60, 0xbd, 0x90, 0x91,
-	0xe8, 0x4f, 0xac, 0x20, 0x18, 0x3b, 0xc1, 0xc5, 0x04, 0xeb, 0x45, 0x77, 0x00, 0x88, 0xcb, 0x8f,
-	0xb9, 0x01, 0x6c, 0x58, 0x26, 0x3f, 0x9b, 0x27, 0xdb, 0x60, 0x3c, 0xb4, 0xcf, 0xc2, 0x86, 0xed,
-	0x3d, 0xa8, 0x0f, 0xff, 0x25, 0xe1, 0xc6, 0xa2, 0x84, 0x8f, 0x70, 0x2e, 0x68, 0xfa, 0x8b, 0xd9,
-	0x41, 0x29, 0xc0, 0x5b, 0xc0, 0xfa, 0xf6, 0x06, 0x0f, 0xfe, 0x13, 0xac, 0x8f, 0xf8, 0x88, 0x9a,
-	0x30, 0xc4, 0x47, 0x88, 0xc7, 0xb4, 0x66, 0x80, 0x78, 0xcc, 0xd7, 0x71, 0xb1, 0xda, 0x43, 0x12,
-	0x5a, 0x1a, 0x13, 0x7a, 0xbc, 0x83, 0xcb, 0xd8, 0x7b, 0x59, 0x7d, 0xb8, 0x0c, 0x66, 0x0c, 0xfb,
-	0x0d, 0xf0, 0x89, 0xfb, 0xac, 0x4e, 0x9f, 0xe9, 0xcd, 0xdf, 0x00, 0x00, 0x00, 0xff, 0xff, 0x3a,
-	0xb6, 0x34, 0xb7, 0xde, 0x04, 0x00, 0x00,
+	// 1142 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x56, 0x5f, 0x8f, 0xdb, 0x44,
+	0x10, 0xc7, 0x67, 0x3b, 0x7f, 0x26, 0x77, 0x47, 0xb5, 0x2a, 0x60, 0xa5, 0xa0, 0x46, 0x16, 0x85,
+	0x50, 0x21, 0x57, 0x0a, 0x6d, 0x55, 0x01, 0x42, 0xca, 0x35, 0x2d, 0x17, 0xa9, 0xb9, 0x44, 0x9b,
+	0xf6, 0x7a, 0x7d, 0xdc, 0x26, 0x7b, 0x97, 0xa5, 0x89, 0x7d, 0xb5, 0xf7, 0xda, 0x5c, 0xbf, 0x00,
+	0x0f, 0xbc, 0x21, 0xf1, 0x88, 0x78, 0xe2, 0x0b, 0xf4, 0x13, 0xa2, 0x99, 0x5d, 0x3b, 0x76, 0x39,
+	0xb8, 0xc2, 0xdb, 0xfe, 0xe6, 0xef, 0xce, 0x6f, 0x66, 0xc7, 0x86, 0xdd, 0x95, 0xd4, 0x62, 0x2e,
+	0xb4, 0x88, 0x4e, 0xd3, 0x44, 0x27, 0xcc, 0x43, 0xdc, 0xbe, 0x7e, 0x92, 0x24, 0x27, 0x4b, 0x79,
+	0x8b, 0x64, 0xcf, 0xcf, 0x8e, 0x6f, 0x69, 0xb5, 0x92, 0x99, 0x16, 0xab, 0x53, 0x63, 0x16, 0xa6,
+	0x00, 0x4f, 0x55, 0x2a, 0xc7, 0xcf, 0x7f, 0x92, 0x33, 0xcd, 0x22, 0x68, 0x1c, 0x26, 0x4b, 0xa1,
+	0xd5, 0x52, 0x06, 0x4e, 0xc7, 0xe9, 0xb6, 0x7a, 0x2c, 0xc2, 0x38, 0x51, 0x2e, 0x1d, 0x49, 0x2d,
+	0x78, 0x61, 0xc3, 0x6e, 0x03, 0x4c, 0x64, 0x9a, 0xa9, 0x4c, 0xcb, 0x58, 0x07, 0x5b, 0xe4, 0x71,
+	0xd5, 0x78, 0x6c, 0xe4, 0xe4, 0x53, 0xb2, 0x0b, 0xdf, 0xc0, 0x76, 0x39, 0x1e, 0x63, 0xe0, 0x4d,
+	0x84, 0x5e, 0x50, 0xc6, 0x26, 0xa7, 0x33, 0xca, 0xa6, 0x8b, 0xe4, 0x35, 0xc5, 0x6c, 0x72, 0x3a,
+	0xb3, 0x9b, 0xd0, 0x78, 0xa8, 0x96, 0x72, 0x18, 0x1f, 0x27, 0x81, 0x4b, 0xb9, 0x76, 0x4d, 0xae,
+	0x5c, 0xca, 0x0b, 0x3d, 0xfb, 0x18, 0x6a, 0x13, 0x91, 0xe2, 0xad, 0x3c, 0x8a, 0x60, 0x51, 0xf8,
+	0xab, 0xb3, 0x09, 0x82, 0x49, 0x0e, 0xc4, 0x4a, 0xe6, 0x89, 0xf1, 0x4c, 0x89, 0xd5, 0x1b, 0x49,
+	0x89, 0x5d, 0x4e, 0x67, 0x94, 0x8d, 0x92, 0xb9, 0xa4, 0xa4, 0x3b, 0x9c, 0xce, 0xec, 0x36, 0xd4,
+	0x47, 0xc9, 0xfc, 0xb1, 0x5a, 0x49, 0xca, 0xd0, 0xea, 0xb5, 0x23, 0xc3, 0x75, 0x94, 0x73, 0x1d,
+	0x3d, 0xce, 0xb9, 0xe6, 0xb9, 0x29, 0xbb, 0x0a, 0xfe, 0x30, 0x1b, 0xa8, 0x34, 0xf0, 0x3b, 0x4e,
+	0xb7, 0xc1, 0x0d, 0x08, 0xff, 0xf4, 0x60, 0xb7, 0xca, 0x17, 0x6b, 0x43, 0x03, 0x6b, 0xe6, 0x49,
+	0xa2, 0xe9, 0x7a, 0x0d, 0x5e, 0x60, 0xe4, 0xe1, 0x7e, 0x12, 0xeb, 0x54, 0xcc, 0x72, 0xce, 0x2d,
+	0x0f, 0xb9, 0x94, 0x17, 0x7a, 0xf6, 0x29, 0x34, 0x87, 0x71, 0xa6, 0x45, 0x3c, 0x93, 0x59, 0xe0,
+	0x76, 0xdc, 0x6e, 0x93, 0x6f, 0x04, 0xec, 0x07, 0x68, 0x3c, 0xc9, 0x64, 0x3a, 0x10, 0x5a, 0x04,
+	0x5e, 0xc7, 0xed, 0xb6, 0x7a, 0xe1, 0x45, 0xdd, 0x8b, 0x72, 0xa3, 0x07, 0xb1, 0x4e, 0xcf, 0x79,
+	0xe1, 0xc3, 0xbe, 0x84, 0xfa, 0x70, 0x75, 0x42, 0x0d, 0xf1, 0xe9, 0x22, 0x3b, 0xc6, 0xdd, 0x0a,
+	0x79, 0xae, 0x65, 0xd7, 0xc0, 0x9d, 0xca, 0x97, 0x41, 0x8d, 0x8c, 0x9a, 0xc6, 0x68, 0x2a, 0x5f,
+	0x72, 0x94, 0x22, 0x29, 0x4f, 0xe2, 0x4c, 0xea, 0xa0, 0x4e, 0xf7, 0x33, 0x00, 0x6f, 0x7e, 0x90,
+	0x0c, 0xe3, 0x85, 0x4c, 0x95, 0x0e, 0x1a, 0xe6, 0xe6, 0x85, 0x00, 0xb5, 0xc8, 0xc7, 0x04, 0xb9,
+	0x0e, 0x9a, 0xd4, 0xbf, 0x8d, 0x00, 0xd9, 0xe3, 0xf2, 0x95, 0xca, 0x54, 0x12, 0x07, 0xd0, 0x71,
+	0xba, 0x1e, 0x2f, 0x30, 0x0b, 0xa0, 0x7e, 0x88, 0xd5, 0x25, 0x71, 0xd0, 0xa2, 0x7e, 0xe6, 0x90,
+	0x78, 0x5d, 0xc8, 0xd9, 0x8b, 0xec, 0x6c, 0x15, 0x6c, 0x57, 0x78, 0xb5, 0x52, 0x5e, 0xe8, 0xd9,
+	0x5d, 0xd8, 0x3e, 0x94, 0xa9, 0x3a, 0x56, 0x33, 0xa1, 0x31, 0xd4, 0x4e, 0xe5, 0xb5, 0x94, 0x34,
+	0xbc, 0x62, 0xd7, 0xfe, 0x0e, 0x76, 0x2a, 0x64, 0xb2, 0x2b, 0xe0, 0xbe, 0x90, 0xe7, 0x76, 0x04,
+	0xf1, 0x88, 0x74, 0xbc, 0x12, 0xcb, 0x33, 0x69, 0x67, 0xdf, 0x80, 0x6f, 0xb7, 0xee, 0x39, 0xe1,
+	0xbd, 0x4d, 0xe3, 0xb1, 0x8c, 0x89, 0xd0, 0x5a, 0xa6, 0xb1, 0xf5, 0xcd, 0x21, 0x4e, 0xeb, 0x40,
+	0xa5, 0x59, 0xb0, 0x45, 0x9c, 0xd1, 0x39, 0xfc, 0x65, 0xab, 0xe8, 0x14, 0xc6, 0x7f, 0xaa, 0xe6,
+	0xf6, 0xbd, 0xf9, 0xdc, 0x00, 0x7c, 0x30, 0xfb, 0x52, 0x9d, 0x2c, 0xcc, 0x48, 0xf9, 0xdc, 0x22,
+	0x94, 0x3f, 0x4c, 0xd2, 0x95, 0xd0, 0x34, 0xfd, 0x4d, 0x6e, 0x11, 0x52, 0x7c, 0x7f, 0x21, 0xe2,
+	0x58, 0x2e, 0x33, 0x7a, 0x00, 0x3e, 0x2f, 0x30, 0xea, 0xf6, 0x94, 0x1e, 0xc8, 0x53, 0xbd, 0xa0,
+	0xb9, 0xf0, 0x79, 0x81, 0x59, 0x07, 0x5a, 0x13, 0xb5, 0x96, 0xcb, 0x7e, 0x76, 0x2a, 0x67, 0x9a,
+	0x26, 0xc2, 0xe1, 0x65, 0x11, 0xfb, 0x1a, 0x00, 0xe9, 0x79, 0xaa, 0xe2, 0x79, 0xf2, 0x3a, 0xa8,
+	0x13, 0xb1, 0xdb, 0x86, 0x58, 0x23, 0xe3, 0x25, 0x3d, 0xeb, 0xc1, 0xce, 0x40, 0x65, 0xa7, 0x4b,
+	0x71, 0x6e, 0x1d, 0x1a, 0x17, 0x38, 0x54, 0x4d, 0xc2, 0x23, 0xa8, 0x59, 0x6f, 0x06, 0xde, 0xd1,
+	0x48, 0xc5, 0x96, 0x0a, 0x3a, 0xa3, 0xec, 0x19, 0xca, 0x0c, 0x0f, 0xde, 0x33, 0x2b, 0x3b, 0x1a,
+	0x89, 0x75, 0xe0, 0xe6, 0x76, 0x62, 0x6d, 0xec, 0xc4, 0xda, 0x56, 0x4f, 0xe7, 0xf0, 0xad, 0xb3,
+	0x99, 0x21, 0xa4, 0x6e, 0xba, 0xdf, 0xef, 0xdd, 0xb9, 0x6b, 0x3b, 0x64, 0x11, 0x36, 0xe0, 0xe8,
+	0x68, 0xff, 0xee, 0xed, 0xbc, 0xc1, 0x04, 0x8a, 0xc5, 0xe3, 0x96, 0x16, 0xcf, 0xff, 0x5b, 0x32,
+	0x11, 0x78, 0xe4, 0xe2, 0x5f, 0xea, 0x42, 0x76, 0x61, 0x5a, 0x9d, 0x65, 0x6c, 0xdf, 0x48, 0xc4,
+	0xea, 0x58, 0x66, 0xda, 0xde, 0xbc, 0xc0, 0x54, 0x93, 0x16, 0xfa, 0x2c, 0xb3, 0x97, 0xb7, 0xa8,
+	0xc8, 0xe9, 0xbe, 0x67, 0xce, 0xdf, 0x1d, 0xda, 0x08, 0x34, 0x2a, 0x22, 0x93, 0xa5, 0x35, 0x5c,
+	0x60, 0xe4, 0xe9, 0xa1, 0x4a, 0xb3, 0x7c, 0x22, 0x0d, 0x40, 0x9e, 0x1e, 0x89, 0x4c, 0xe7, 0xad,
+	0xc0, 0xb3, 0x79, 0x0c, 0xf3, 0xb9, 0x8a, 0x4f, 0x6c, 0x37, 0x72, 0x88, 0x7b, 0xe2, 0xc1, 0x5a,
+	0xcb, 0x98, 0xde, 0xbb, 0x6f, 0xf6, 0x44, 0x21, 0x40, 0xbf, 0x91, 0xca, 0x32, 0xf4, 0xab, 0x75,
+	0x5c, 0xf4, 0xb3, 0x30, 0xfc, 0xcd, 0x01, 0x7f, 0x18, 0xcf, 0xe5, 0xba, 0xbc, 0x2f, 0x9c, 0xea,
+	0xbe, 0xf8, 0xaa, 0xf4, 0xd0, 0x5a, 0xbd, 0x8f, 0xec, 0xea, 0x43, 0xa7, 0x08, 0xe5, 0x66, 0x59,
+	0x92, 0x49, 0xfb, 0x47, 0x68, 0x16, 0xa2, 0x0b, 0x9e, 0xfc, 0xe7, 0xe5, 0x27, 0x5f, 0xac, 0x1d,
+	0x0a, 0x35, 0x50, 0x69, 0x79, 0x05, 0xfc, 0xb1, 0x05, 0x8d, 0x5c, 0x5e, 0x1e, 0x0f, 0xe7, 0xfd,
+	0xc7, 0xe3, 0x7b, 0x68, 0xe1, 0x52, 0xcf, 0x3d, 0xb7, 0x2e, 0xf5, 0x2c, 0x9b, 0x9b, 0x77, 0xaf,
+	0x96, 0xf3, 0x54, 0xc6, 0xf6, 0x7b, 0x52, 0x60, 0x76, 0x07, 0xea, 0x58, 0xa1, 0x92, 0x99, 0xfd,
+	0x9a, 0x5c, 0xab, 0x16, 0x12, 0x59, 0xad, 0x61, 0x26, 0xb7, 0x6d, 0x3f, 0x82, 0xed, 0xb2, 0xe2,
+	0x02, 0x7e, 0xbe, 0xa8, 0xf2, 0x73, 0xa5, 0x14, 0xd6, 0xc4, 0x2a, 0x31, 0xf4, 0xd6, 0x01, 0xd8,
+	0x68, 0x2a, 0x3f, 0x0d, 0xce, 0x25, 0x3f, 0x0d, 0x5d, 0xf0, 0xb0, 0xd4, 0x7f, 0xfd, 0x91, 0x21,
+	0x0b, 0x76, 0xc5, 0x7c, 0xcf, 0x5c, 0xfa, 0x32, 0xe3, 0xf1, 0x5d, 0x56, 0xbd, 0xff, 0xc4, 0x6a,
+	0x78, 0x13, 0x9a, 0xf8, 0x59, 0xe8, 0xa7, 0xa9, 0x38, 0x67, 0x9f, 0x81, 0xd3, 0x0f, 0x1c, 0x22,
+	0xf0, 0x43, 0x73, 0x07, 0xd4, 0x1d, 0x62, 0x85, 0xdc, 0xe9, 0x87, 0x6f, 0x00, 0x10, 0xdb, 0x5f,
+	0xb6, 0x1b, 0xe0, 0x8c, 0xad, 0xf1, 0x27, 0x1b, 0x63, 0xa3, 0x8c, 0xc6, 0x86, 0x1d, 0x67, 0xdc,
+	0x7e, 0x00, 0xb5, 0xf1, 0x3f, 0xb1, 0x7b, 0xa3, 0xca, 0xee, 0xdf, 0x72, 0x96, 0xc8, 0xfd, 0xd9,
+	0x81, 0x66, 0xa1, 0x60, 0xbb, 0xe0, 0xec, 0x99, 0xbf, 0x93, 0xfd, 0x0f, 0xb8, 0xb3, 0x87, 0xf8,
+	0x80, 0x82, 0x38, 0x88, 0x0f, 0x10, 0x4f, 0xcd, 0x67, 0x03, 0xf1, 0x94, 0x5d, 0xc7, 0xc2, 0xbc,
+	0x77, 0x93, 0x50, 0xd1, 0x68, 0xd0, 0x67, 0x1d, 0x2c, 0xc6, 0x2f, 0xf7, 0x78, 0x53, 0x0c, 0x5a,
+	0x8c, 0xf7, 0xea, 0xe0, 0x53, 0xee, 0xe7, 0x35, 0xa2, 0xf4, 0x9b, 0xbf, 0x06, 0x00, 0x12, 0x33,
+	0xc9, 0xc3, 0xf8, 0x0a, 0x00, 0x00,
 }
@@ -0,0 +1,188 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const journalFileName = "journal"
+
+// JournalEntry records a single metadata write. Every show keeps its journal
+// in '.teflon/journal' in the show root, one JSON encoded entry per line.
+type JournalEntry struct {
+	Time     time.Time
+	User     string
+	Host     string
+	Path     string
+	Revision uint64
+	Changes  []MetaChange
+}
+
+// MetaChange is the change of a single user metadata key. A nil Old means the
+// key was added, a nil New means it was deleted.
+type MetaChange struct {
+	Key string
+	Old *string `json:",omitempty"`
+	New *string `json:",omitempty"`
+}
+
+// JournalFilter selects journal entries. Zero values match everything.
+type JournalFilter struct {
+	Keys  []string
+	User  string
+	Since time.Time
+	Until time.Time
+}
+
+// JournalFile() returns the path of the journal of the object's show.
+func (o *TeflonObject) JournalFile() string {
+	if o.Show == nil {
+		return ""
+	}
+	return filepath.Join(o.Show.Path, teflonDirName, journalFileName)
+}
+
+// Appends the changes between the old and the current user metadata to the
+// journal. Objects outside of shows have no journal.
+func (o *TeflonObject) journal(old map[string]string) error {
+	if o.Show == nil {
+		return nil
+	}
+	chs := diffUserData(old, o.UserData)
+	if len(chs) == 0 {
+		return nil
+	}
+
+	e := JournalEntry{
+		Time:     time.Now().UTC(),
+		User:     currentUser(),
+		Path:     ShowAbs(o.Path),
+		Revision: o.Revision,
+		Changes:  chs,
+	}
+	e.Host, _ = os.Hostname()
+
+	out, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	l, err := LockFile(o.JournalFile())
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	f, err := os.OpenFile(o.JournalFile(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(out, '\n'))
+	return err
+}
+
+// History() returns the journal entries of the object matching the filter, in
+// chronological order.
+func (o *TeflonObject) History(f *JournalFilter) ([]JournalEntry, error) {
+	res := []JournalEntry{}
+	if o.Show == nil || !Exist(o.JournalFile()) {
+		return res, nil
+	}
+	sap := ShowAbs(o.Path)
+
+	jf, err := os.Open(o.JournalFile())
+	if err != nil {
+		return nil, err
+	}
+	defer jf.Close()
+
+	sc := bufio.NewScanner(jf)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		e := JournalEntry{}
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, err
+		}
+		if e.Path != sap || !f.match(&e) {
+			continue
+		}
+		res = append(res, e)
+	}
+	return res, sc.Err()
+}
+
+// Tells if an entry matches the filter. Changes of keys not in the filter are
+// removed from the entry.
+func (f *JournalFilter) match(e *JournalEntry) bool {
+	if f == nil {
+		return true
+	}
+	if f.User != "" && f.User != e.User {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+	if len(f.Keys) > 0 {
+		chs := []MetaChange{}
+		for _, ch := range e.Changes {
+			if contains(f.Keys, ch.Key) {
+				chs = append(chs, ch)
+			}
+		}
+		e.Changes = chs
+	}
+	return len(e.Changes) > 0
+}
+
+// Returns the changes between two versions of user metadata sorted by key.
+func diffUserData(old, new map[string]string) []MetaChange {
+	chs := []MetaChange{}
+	for k, ov := range old {
+		ov := ov
+		if nv, ok := new[k]; !ok {
+			chs = append(chs, MetaChange{Key: k, Old: &ov})
+		} else if nv != ov {
+			nv := nv
+			chs = append(chs, MetaChange{Key: k, Old: &ov, New: &nv})
+		}
+	}
+	for k, nv := range new {
+		nv := nv
+		if _, ok := old[k]; !ok {
+			chs = append(chs, MetaChange{Key: k, New: &nv})
+		}
+	}
+	sort.Slice(chs, func(i, j int) bool { return chs[i].Key < chs[j].Key })
+	return chs
+}
+
+// Returns the name of the OS user running the process.
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
@@ -0,0 +1,120 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+)
+
+const lockExtension = ".lock"
+
+// The lock files of stores without Teflon directories are kept here, under the
+// show root.
+const lockDirName = ".teflon/locks"
+
+// LockTimeout is the time a process waits for a lock before giving up.
+var LockTimeout = 30 * time.Second
+
+// StaleLockAge is the age after which a lock is considered to be left behind by
+// a crashed process and gets removed.
+var StaleLockAge = 5 * time.Minute
+
+// ErrLockTimeout is returned when a lock couldn't be acquired in time.
+var ErrLockTimeout = errors.New("Timed out waiting for lock.")
+
+// FileLock is an advisory lock on a file. The lock is represented by a lock
+// file next to the locked file, created exclusively. Exclusive creation is
+// atomic on local file-systems and on NFS (version 3 or later) as well, which is
+// not true for flock(2) style locks. The lock file identifies its holder, and
+// its modification time is refreshed while it's held.
+type FileLock struct {
+	path string
+	done chan struct{}
+}
+
+// LockFile() acquires the lock of a file, waiting at most LockTimeout for it.
+func LockFile(fspath string) (*FileLock, error) {
+	lp := fspath + lockExtension
+	host, _ := os.Hostname()
+	deadline := time.Now().Add(LockTimeout)
+	for {
+		f, err := os.OpenFile(lp, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%s %d %d\n", host, os.Getpid(), rand.Int63())
+			f.Close()
+			l := &FileLock{path: lp, done: make(chan struct{})}
+			go l.refresh()
+			return l, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		// The holder is read before the age, so a lock replaced in between isn't
+		// mistaken for the stale one.
+		holder, err := ioutil.ReadFile(lp)
+		if err != nil {
+			continue
+		}
+		if fi, err := os.Stat(lp); err == nil && time.Since(fi.ModTime()) > StaleLockAge {
+			removeStaleLock(lp, holder)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, ErrLockTimeout
+		}
+		time.Sleep(time.Duration(20+rand.Intn(80)) * time.Millisecond)
+	}
+}
+
+// Removes a stale lock file. Other waiters may find the same lock stale and
+// replace it with their own, so it's only removed if it still has the holder it
+// was found stale with.
+func removeStaleLock(lp string, holder []byte) {
+	if cur, err := ioutil.ReadFile(lp); err != nil || !bytes.Equal(cur, holder) {
+		return
+	}
+	log.Println("WARNING: Removing stale lock:", lp)
+	os.Remove(lp)
+}
+
+// Keeps the lock file fresh until the lock is released, so locks held longer
+// than StaleLockAge aren't taken for stale ones.
+func (l *FileLock) refresh() {
+	t := time.NewTicker(StaleLockAge / 3)
+	defer t.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-t.C:
+			now := time.Now()
+			os.Chtimes(l.path, now, now)
+		}
+	}
+}
+
+// Unlock() releases the lock.
+func (l *FileLock) Unlock() error {
+	close(l.done)
+	return os.Remove(l.path)
+}
@@ -0,0 +1,376 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gradient-images/teflon/internal/meta"
+
+	protobuf "github.com/golang/protobuf/proto"
+)
+
+// Meta store names, used by the MetaStore field of the show config.
+const (
+	StoreSidecar = "sidecar"
+	StoreXattr   = "xattr"
+	StoreMemory  = "memory"
+)
+
+// MetaStore is where the metadata of objects is kept. Stores only keep and
+// return metadata, format migration, revisions and the journal are handled by
+// the caller.
+type MetaStore interface {
+	// Has() tells if the object has stored metadata.
+	Has(o *TeflonObject) bool
+
+	// Read() reads the stored metadata of the object into pm as it is, without
+	// migrating it to the current format version.
+	Read(o *TeflonObject, pm *meta.PersistentMeta) error
+
+	// Write() replaces the stored metadata of the object. Stores that keep
+	// metadata in encoded form use the given format.
+	Write(o *TeflonObject, pm *meta.PersistentMeta, format string) error
+
+	// Delete() removes the stored metadata of the object. Deleting missing
+	// metadata is not an error.
+	Delete(o *TeflonObject) error
+
+	// Lock() locks the metadata of the object for a read-modify-write cycle.
+	Lock(o *TeflonObject) (MetaLock, error)
+}
+
+// MetaLock is a lock acquired by MetaStore.Lock().
+type MetaLock interface {
+	Unlock() error
+}
+
+// MemStore is the in-memory store used by shows with the 'memory' meta store.
+// It doesn't outlive the process, so it's only useful for tests.
+var MemStore = NewMemoryStore()
+
+// metaStores associates meta store names of the show config to stores.
+var metaStores = map[string]MetaStore{
+	"":           SidecarStore{},
+	StoreSidecar: SidecarStore{},
+	StoreXattr:   XattrStore{},
+	StoreMemory:  MemStore,
+}
+
+// MetaStore() returns the store of the object's metadata, as set in the config
+// of its show. Show roots and objects outside of shows always use sidecar
+// files, since the show config lives there too.
+func (o *TeflonObject) MetaStore() (MetaStore, error) {
+	if o.Show == nil || o.Show == o || o.ShowRoot {
+		return SidecarStore{}, nil
+	}
+	c, err := o.Show.Config()
+	if err != nil {
+		return nil, err
+	}
+	st := metaStores[c.MetaStore]
+	if _, ok := st.(SidecarStore); !ok && o.FileInfo.IsDir && sidecarShowRoot(o) {
+		return SidecarStore{}, nil
+	}
+	return st, nil
+}
+
+// Tells if the object is the root of a show nested in a show that doesn't use
+// sidecar files.
+func sidecarShowRoot(o *TeflonObject) bool {
+	if !Exist(o.MetaFile()) {
+		return false
+	}
+	pm := &meta.PersistentMeta{}
+	return readMetaFile(o.MetaFile(), pm) == nil && pm.ShowRoot
+}
+
+// HasMeta() tells if the object has stored metadata.
+func (o *TeflonObject) HasMeta() bool {
+	st, err := o.MetaStore()
+	return err == nil && st.Has(o)
+}
+
+// Locks the metadata of the object in its store.
+func (o *TeflonObject) lockMeta() (MetaLock, error) {
+	st, err := o.MetaStore()
+	if err != nil {
+		return nil, err
+	}
+	return st.Lock(o)
+}
+
+// ChangeMetaStore() moves the metadata of the objects of the show, including
+// single frames of sequences, from their current store to the named one, and
+// records the new store in the show config. The object has to be a show root.
+// Emptied '.teflon' directories are removed. It returns the number of objects
+// moved.
+func (o *TeflonObject) ChangeMetaStore(name string) (int, error) {
+	if !o.ShowRoot {
+		return 0, errors.New("Not a show root: " + o.Path)
+	}
+	to, ok := metaStores[name]
+	if !ok {
+		return 0, errors.New("Unknown meta store: " + name)
+	}
+	c, err := o.Config()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	move := func(d *TeflonObject) error {
+		ok, err := moveStoredMeta(d, to, c.MetaFormat)
+		if ok {
+			count++
+		}
+		return err
+	}
+	err = o.Walk(func(d *TeflonObject) error {
+		// Show roots keep their sidecar files.
+		if d.ShowRoot || d.Show != o {
+			return nil
+		}
+		if err := move(d); err != nil {
+			return err
+		}
+		for _, n := range d.Frames() {
+			f, err := NewTeflonObject(d.FramePath(n))
+			if err != nil {
+				return err
+			}
+			if err := move(f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	c.MetaStore = name
+	return count, o.SaveConfig(c)
+}
+
+// Moves the stored metadata of an object to another store as it is, and tells
+// if there was anything to move.
+func moveStoredMeta(o *TeflonObject, to MetaStore, format string) (bool, error) {
+	from, err := o.MetaStore()
+	if err != nil || from == to || !from.Has(o) {
+		return false, err
+	}
+	l, err := from.Lock(o)
+	if err != nil {
+		return false, err
+	}
+	pm := &meta.PersistentMeta{}
+	err = from.Read(o, pm)
+	if err == nil {
+		err = to.Write(o, pm, format)
+	}
+	if err == nil {
+		err = from.Delete(o)
+	}
+	l.Unlock()
+	if err != nil {
+		return false, err
+	}
+	if _, ok := from.(SidecarStore); ok {
+		// Fails harmlessly while the directory isn't empty.
+		os.Remove(filepath.Dir(o.MetaFile()))
+	}
+	return true, nil
+}
+
+// SidecarStore keeps metadata in meta files in the '.teflon' directory next to
+// the objects. See MetaFile().
+type SidecarStore struct{}
+
+func (SidecarStore) Has(o *TeflonObject) bool {
+	return Exist(o.MetaFile())
+}
+
+func (SidecarStore) Read(o *TeflonObject, pm *meta.PersistentMeta) error {
+	in, err := ioutil.ReadFile(o.MetaFile())
+	if err != nil {
+		return err
+	}
+	return decodeMeta(in, pm)
+}
+
+func (SidecarStore) Write(o *TeflonObject, pm *meta.PersistentMeta, format string) error {
+	out, err := encodeMeta(pm, format)
+	if err != nil {
+		return err
+	}
+	if err := o.createTeflonDir(); err != nil {
+		return err
+	}
+	return writeFileAtomic(o.MetaFile(), out, 0644)
+}
+
+func (SidecarStore) Delete(o *TeflonObject) error {
+	err := os.Remove(o.MetaFile())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (SidecarStore) Lock(o *TeflonObject) (MetaLock, error) {
+	if err := o.createTeflonDir(); err != nil {
+		return nil, err
+	}
+	return LockFile(o.MetaFile())
+}
+
+// ErrXattrUnsupported is returned by the XattrStore on platforms without
+// extended attribute support.
+var ErrXattrUnsupported = errors.New("Extended attributes are not supported on this platform.")
+
+// Prefix of the extended attributes written by the XattrStore.
+const xattrPrefix = "user.teflon."
+
+// XattrStore keeps metadata in the 'user.teflon.meta' extended attribute of
+// the objects, so no '.teflon' directories are created. Sequences don't exist
+// as a single file, their metadata is kept in the 'user.teflon.seq.<name>'
+// attribute of their directory. Locks are taken with lock files in the
+// '.teflon/locks' directory of the show root. File-systems usually limit the
+// size of attributes to a few kilobytes, so the compact protobuf format is
+// recommended.
+type XattrStore struct{}
+
+// Returns the file and the name of the attribute holding the object's
+// metadata.
+func xattrTarget(o *TeflonObject) (string, string) {
+	if o.IsSeq() || (parseSeqName(filepath.Base(o.Path)) != nil && !Exist(o.Path)) {
+		return filepath.Dir(o.Path), xattrPrefix + "seq." + filepath.Base(o.Path)
+	}
+	return o.Path, xattrPrefix + "meta"
+}
+
+func (XattrStore) Has(o *TeflonObject) bool {
+	f, a := xattrTarget(o)
+	_, err := getXattr(f, a)
+	return err == nil
+}
+
+func (XattrStore) Read(o *TeflonObject, pm *meta.PersistentMeta) error {
+	f, a := xattrTarget(o)
+	in, err := getXattr(f, a)
+	if err != nil {
+		return err
+	}
+	return decodeMeta(in, pm)
+}
+
+func (XattrStore) Write(o *TeflonObject, pm *meta.PersistentMeta, format string) error {
+	out, err := encodeMeta(pm, format)
+	if err != nil {
+		return err
+	}
+	f, a := xattrTarget(o)
+	return setXattr(f, a, out)
+}
+
+func (XattrStore) Delete(o *TeflonObject) error {
+	f, a := xattrTarget(o)
+	return removeXattr(f, a)
+}
+
+// The lock files are named after the escaped show relative path of the objects,
+// so they don't show up among the files of the show.
+func (XattrStore) Lock(o *TeflonObject) (MetaLock, error) {
+	rel, err := filepath.Rel(o.Show.Path, o.Path)
+	if err != nil {
+		return nil, err
+	}
+	ld := filepath.Join(o.Show.Path, lockDirName)
+	if err := os.MkdirAll(ld, 0755); err != nil {
+		return nil, err
+	}
+	return LockFile(filepath.Join(ld, url.PathEscape(filepath.ToSlash(rel))))
+}
+
+// MemoryStore keeps metadata in memory by the paths of the objects.
+type MemoryStore struct {
+	mu    sync.Mutex
+	metas map[string]*meta.PersistentMeta
+	locks map[string]*sync.Mutex
+}
+
+// NewMemoryStore() creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		metas: map[string]*meta.PersistentMeta{},
+		locks: map[string]*sync.Mutex{},
+	}
+}
+
+func (s *MemoryStore) Has(o *TeflonObject) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.metas[o.Path]
+	return ok
+}
+
+func (s *MemoryStore) Read(o *TeflonObject, pm *meta.PersistentMeta) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.metas[o.Path]
+	if !ok {
+		return os.ErrNotExist
+	}
+	protobuf.Merge(pm, m)
+	return nil
+}
+
+func (s *MemoryStore) Write(o *TeflonObject, pm *meta.PersistentMeta, format string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metas[o.Path] = protobuf.Clone(pm).(*meta.PersistentMeta)
+	return nil
+}
+
+func (s *MemoryStore) Delete(o *TeflonObject) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.metas, o.Path)
+	return nil
+}
+
+func (s *MemoryStore) Lock(o *TeflonObject) (MetaLock, error) {
+	s.mu.Lock()
+	l, ok := s.locks[o.Path]
+	if !ok {
+		l = &sync.Mutex{}
+		s.locks[o.Path] = l
+	}
+	s.mu.Unlock()
+	l.Lock()
+	return memLock{l}, nil
+}
+
+type memLock struct{ l *sync.Mutex }
+
+func (l memLock) Unlock() error {
+	l.l.Unlock()
+	return nil
+}
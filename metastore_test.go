@@ -0,0 +1,162 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gradient-images/teflon/internal/meta"
+)
+
+// Creates an object for a new file in a temporary directory. The directory
+// stands in for the show root, which the XattrStore keeps its locks in. It has
+// to be removed by the caller.
+func testObject(t *testing.T, name string) (*TeflonObject, string) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "teflon-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fspath := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(fspath, []byte("data\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	o, err := NewTeflonObject(fspath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.Show = &TeflonObject{Path: dir}
+	o.Show.ShowRoot = true
+	return o, dir
+}
+
+// Skips the test if the store keeps metadata in extended attributes and the
+// file-system of the object doesn't support them.
+func skipUnsupported(t *testing.T, st MetaStore, o *TeflonObject) {
+	t.Helper()
+	if _, ok := st.(XattrStore); !ok {
+		return
+	}
+	err := setXattr(o.Path, xattrPrefix+"test", nil)
+	if err == syscall.ENOTSUP || err == ErrXattrUnsupported {
+		t.Skip("Extended attributes are not supported:", err)
+	}
+	removeXattr(o.Path, xattrPrefix+"test")
+}
+
+var testStores = []struct {
+	name  string
+	store MetaStore
+}{
+	{StoreMemory, NewMemoryStore()},
+	{StoreSidecar, SidecarStore{}},
+	{StoreXattr, XattrStore{}},
+}
+
+func TestMetaStore(t *testing.T) {
+	for _, ts := range testStores {
+		t.Run(ts.name, func(t *testing.T) {
+			st := ts.store
+			o, dir := testObject(t, "a.txt")
+			defer os.RemoveAll(dir)
+			skipUnsupported(t, st, o)
+
+			if st.Has(o) {
+				t.Fatal("Has() is true before Write()")
+			}
+			if err := st.Read(o, &meta.PersistentMeta{}); err == nil {
+				t.Fatal("Read() of missing metadata succeeded")
+			}
+			if err := st.Delete(o); err != nil {
+				t.Fatal("Delete() of missing metadata failed:", err)
+			}
+
+			for _, f := range []string{FormatProtobuf, FormatJSON, FormatYAML} {
+				pm := &meta.PersistentMeta{
+					Revision: 3,
+					UserData: map[string]string{"status": "wip", "format": f},
+					Unset:    []string{"fps"},
+				}
+				if err := st.Write(o, pm, f); err != nil {
+					t.Fatalf("Write() in %s failed: %v", f, err)
+				}
+				if !st.Has(o) {
+					t.Fatal("Has() is false after Write()")
+				}
+				got := &meta.PersistentMeta{}
+				if err := st.Read(o, got); err != nil {
+					t.Fatal("Read() failed:", err)
+				}
+				if got.Revision != 3 || got.UserData["status"] != "wip" ||
+					got.UserData["format"] != f || len(got.Unset) != 1 || got.Unset[0] != "fps" {
+					t.Fatalf("Read() returned %v, wrote %v", got, pm)
+				}
+			}
+
+			if err := st.Delete(o); err != nil {
+				t.Fatal("Delete() failed:", err)
+			}
+			if st.Has(o) {
+				t.Fatal("Has() is true after Delete()")
+			}
+		})
+	}
+}
+
+func TestMetaStoreLock(t *testing.T) {
+	for _, ts := range testStores {
+		t.Run(ts.name, func(t *testing.T) {
+			st := ts.store
+			o, dir := testObject(t, "a.txt")
+			defer os.RemoveAll(dir)
+			skipUnsupported(t, st, o)
+
+			l, err := st.Lock(o)
+			if err != nil {
+				t.Fatal("Lock() failed:", err)
+			}
+			locked := make(chan MetaLock)
+			go func() {
+				l2, err := st.Lock(o)
+				if err != nil {
+					t.Error("Second Lock() failed:", err)
+				}
+				locked <- l2
+			}()
+
+			select {
+			case <-locked:
+				t.Fatal("Second Lock() succeeded while locked")
+			case <-time.After(200 * time.Millisecond):
+			}
+
+			if err := l.Unlock(); err != nil {
+				t.Fatal("Unlock() failed:", err)
+			}
+			select {
+			case l2 := <-locked:
+				if l2 != nil {
+					l2.Unlock()
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Second Lock() didn't succeed after Unlock()")
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gradient-images/teflon/internal/meta"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+)
+
+// MHLExtension is the extension of ASC Media Hash List files.
+const MHLExtension = ".mhl"
+
+// Date format of MHL files.
+const mhlTimeFormat = "2006-01-02T15:04:05Z"
+
+// MHL is an ASC Media Hash List (version 1.1) manifest.
+type MHL struct {
+	XMLName xml.Name   `xml:"hashlist"`
+	Version string     `xml:"version,attr"`
+	Creator MHLCreator `xml:"creatorinfo"`
+	Hashes  []MHLHash  `xml:"hash"`
+}
+
+// MHLCreator describes who created an MHL manifest and when.
+type MHLCreator struct {
+	Name       string `xml:"name,omitempty"`
+	Username   string `xml:"username"`
+	Hostname   string `xml:"hostname"`
+	Tool       string `xml:"tool"`
+	StartDate  string `xml:"startdate"`
+	FinishDate string `xml:"finishdate"`
+}
+
+// MHLHash is the entry of a single file in an MHL manifest. Paths are relative
+// to the directory of the manifest. Teflon writes xxHash64 checksums, but MD5
+// and SHA-1 checksums are verified too.
+type MHLHash struct {
+	File                 string `xml:"file"`
+	Size                 int64  `xml:"size"`
+	LastModificationDate string `xml:"lastmodificationdate,omitempty"`
+	MD5                  string `xml:"md5,omitempty"`
+	SHA1                 string `xml:"sha1,omitempty"`
+	XXHash64BE           string `xml:"xxhash64be,omitempty"`
+	HashDate             string `xml:"hashdate,omitempty"`
+}
+
+// ReadMHL() reads an MHL manifest.
+func ReadMHL(fspath string) (*MHL, error) {
+	in, err := ioutil.ReadFile(fspath)
+	if err != nil {
+		return nil, err
+	}
+	m := &MHL{}
+	if err := xml.Unmarshal(in, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Write() writes the manifest to a file.
+func (m *MHL) Write(fspath string) error {
+	out, err := xml.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	out = append([]byte(xml.Header), out...)
+	return writeFileAtomic(fspath, append(out, '\n'), 0644)
+}
+
+// CreateMHL() builds an MHL manifest of the files under the object, which has
+// to be a directory. Checksums are taken from the metadata of the files, and
+// computed first for files that weren't hashed since they last changed. MHL
+// files under the directory are left out. File paths are relative to base, the
+// directory the manifest is written to, which has to be the directory itself or
+// one of its ancestors, so the manifest can be verified where it is.
+func (o *TeflonObject) CreateMHL(base string) (*MHL, error) {
+	if !o.FileInfo.IsDir {
+		return nil, errors.New("Not a directory: " + o.Path)
+	}
+	if !inDir(base, o.Path) {
+		return nil, errors.New("Manifest would be outside of the directory: " + base)
+	}
+	host, _ := os.Hostname()
+	m := &MHL{
+		Version: "1.1",
+		Creator: MHLCreator{
+			Username:  currentUser(),
+			Hostname:  host,
+			Tool:      "teflon",
+			StartDate: time.Now().UTC().Format(mhlTimeFormat),
+		},
+	}
+
+	err := o.WalkFiles(func(f *TeflonObject) error {
+		if strings.HasSuffix(f.Path, MHLExtension) {
+			return nil
+		}
+		if _, err := f.UpdateChecksum(false); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(base, f.Path)
+		if err != nil {
+			return err
+		}
+		m.Hashes = append(m.Hashes, MHLHash{
+			File:                 filepath.ToSlash(rel),
+			Size:                 f.FileInfo.Size,
+			LastModificationDate: mhlTime(f.FileInfo.ModTime),
+			XXHash64BE:           f.Checksum.XXH64,
+			HashDate:             mhlTime(f.Checksum.Time),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.Creator.FinishDate = time.Now().UTC().Format(mhlTimeFormat)
+	return m, nil
+}
+
+// VerifyMHL() checks the files under the directory of an MHL manifest against
+// it. The result is recorded in the Verification section of the metadata of
+// every existing file listed in the manifest. Files that pass and have no
+// checksum yet get their checksum stored too. Files under the directory that
+// are not in the manifest are reported as new. The results are sorted by path.
+func VerifyMHL(fspath string) ([]VerifyResult, error) {
+	m, err := ReadMHL(fspath)
+	if err != nil {
+		return nil, err
+	}
+	root := filepath.Dir(fspath)
+	manifest := filepath.Base(fspath)
+
+	// Received manifests can't make us read or write outside their directory.
+	for _, h := range m.Hashes {
+		f := filepath.FromSlash(h.File)
+		if filepath.IsAbs(f) || path.IsAbs(h.File) || !inDir(root, filepath.Join(root, f)) {
+			return nil, fmt.Errorf("Manifest entry outside of the manifest's directory: %s", h.File)
+		}
+	}
+
+	res := []VerifyResult{}
+	listed := map[string]bool{}
+	for _, h := range m.Hashes {
+		fsp := filepath.Join(root, filepath.FromSlash(h.File))
+		listed[fsp] = true
+		if !Exist(fsp) {
+			res = append(res, VerifyResult{Path: fsp, Status: VerifyMissing})
+			continue
+		}
+		f, err := NewTeflonObject(fsp)
+		if err != nil {
+			return nil, err
+		}
+		st, c, err := verifyMHLHash(f, h)
+		if err != nil {
+			return nil, err
+		}
+		err = f.UpdateMeta(func(f *TeflonObject) error {
+			f.Verification = &meta.Verification{
+				Manifest: manifest,
+				Status:   st,
+				Time:     ptypes.TimestampNow(),
+			}
+			if st == VerifyOK && !f.checksumCurrent() {
+				f.Checksum = c
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, VerifyResult{Path: fsp, Status: st})
+	}
+
+	ro, err := NewTeflonObject(root)
+	if err != nil {
+		return nil, err
+	}
+	err = ro.WalkFiles(func(f *TeflonObject) error {
+		if !listed[f.Path] && !strings.HasSuffix(f.Path, MHLExtension) {
+			res = append(res, VerifyResult{Path: f.Path, Status: VerifyNew})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Path < res[j].Path })
+	return res, nil
+}
+
+// Checks a file against its manifest entry. It also returns the Teflon
+// checksum of the file, so it doesn't have to be read again to store it.
+func verifyMHLHash(f *TeflonObject, h MHLHash) (string, *meta.Checksum, error) {
+	if f.FileInfo.Size != h.Size {
+		return VerifyMismatch, nil, nil
+	}
+
+	r, err := os.Open(f.Path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer r.Close()
+
+	sh, xh, mh, s1 := sha256.New(), newXXH64(), md5.New(), sha1.New()
+	if _, err := io.Copy(io.MultiWriter(sh, xh, mh, s1), r); err != nil {
+		return "", nil, err
+	}
+	hashes := []struct{ want, got string }{
+		{h.XXHash64BE, hex.EncodeToString(xh.Sum(nil))},
+		{h.MD5, hex.EncodeToString(mh.Sum(nil))},
+		{h.SHA1, hex.EncodeToString(s1.Sum(nil))},
+	}
+	checked := false
+	for _, hs := range hashes {
+		if hs.want == "" {
+			continue
+		}
+		if !strings.EqualFold(hs.want, hs.got) {
+			return VerifyMismatch, nil, nil
+		}
+		checked = true
+	}
+	if !checked {
+		return "", nil, errors.New("No supported checksum in manifest for: " + h.File)
+	}
+
+	c := &meta.Checksum{
+		SHA256:  hex.EncodeToString(sh.Sum(nil)),
+		XXH64:   hex.EncodeToString(xh.Sum(nil)),
+		Size:    f.FileInfo.Size,
+		ModTime: f.FileInfo.ModTime,
+		Time:    ptypes.TimestampNow(),
+	}
+	return VerifyOK, c, nil
+}
+
+// Formats a protobuf timestamp for MHL files.
+func mhlTime(ts *timestamp.Timestamp) string {
+	t, err := ptypes.Timestamp(ts)
+	if err != nil {
+		return ""
+	}
+	return t.UTC().Format(mhlTimeFormat)
+}
@@ -0,0 +1,96 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"fmt"
+
+	"github.com/gradient-images/teflon/internal/meta"
+)
+
+// Migration upgrades metadata by one format version. When the meaning of a
+// field changes, the old field has to be kept in metadata.proto (marked as
+// deprecated) so the migration can read it and fill in the new one.
+type Migration func(pm *meta.PersistentMeta) error
+
+// Migrations is the registry of format migrations. Migrations[i] upgrades
+// metadata of version i to version i+1, so the current format version is the
+// length of the registry.
+var Migrations = []Migration{
+	// Version 0 files predate format versioning. Their layout is identical to
+	// version 1, so only the version stamp is added.
+	func(pm *meta.PersistentMeta) error { return nil },
+}
+
+// VersionError is returned when a meta file was written by a newer version of
+// Teflon. Such files are neither read nor overwritten.
+type VersionError struct {
+	Version uint32
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("Meta format version %d is newer than the supported version %d.",
+		e.Version, MetaVersion())
+}
+
+// MetaVersion() returns the current format version of meta files.
+func MetaVersion() uint32 {
+	return uint32(len(Migrations))
+}
+
+// Upgrades metadata to the current format version in memory.
+func migrateMeta(pm *meta.PersistentMeta) error {
+	if pm.Version > MetaVersion() {
+		return &VersionError{Version: pm.Version}
+	}
+	for v := pm.Version; v < MetaVersion(); v++ {
+		if err := Migrations[v](pm); err != nil {
+			return fmt.Errorf("Migration from meta format version %d failed: %v", v, err)
+		}
+	}
+	pm.Version = MetaVersion()
+	return nil
+}
+
+// MigrateMeta() upgrades the object's stored metadata to the current format
+// version, and tells if it was upgraded. Metadata is upgraded in memory when
+// it's read anyway, this only makes the upgrade permanent.
+func (o *TeflonObject) MigrateMeta() (bool, error) {
+	st, err := o.MetaStore()
+	if err != nil || !st.Has(o) {
+		return false, err
+	}
+	l, err := st.Lock(o)
+	if err != nil {
+		return false, err
+	}
+	defer l.Unlock()
+
+	pm := &meta.PersistentMeta{}
+	if err := st.Read(o, pm); err != nil {
+		return false, err
+	}
+	if pm.Version == MetaVersion() {
+		return false, nil
+	}
+	if err := migrateMeta(pm); err != nil {
+		return false, err
+	}
+
+	c, err := o.Config()
+	if err != nil {
+		return false, err
+	}
+	return true, st.Write(o, pm, c.MetaFormat)
+}
@@ -0,0 +1,172 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gradient-images/teflon/internal/meta"
+)
+
+// WriteProtected allows writing keys in protected namespaces. Authorised tools
+// set it before writing metadata.
+var WriteProtected bool
+
+// NamespaceConfig controls which namespaces of user metadata keys 'teflon set'
+// can write. The namespace of a key is the part before its last dot, like
+// 'pipeline' in 'pipeline.publishId'. Namespaces cover their sub-namespaces.
+type NamespaceConfig struct {
+	// Writable lists the namespaces the command line can write. If empty, all
+	// namespaces can be written except the protected ones. Keys without a
+	// namespace can always be written.
+	Writable []string `json:",omitempty"`
+
+	// Protected lists the namespaces only authorised tools write, like
+	// 'pipeline'. Writing them from the command line needs '--protected'.
+	Protected []string `json:",omitempty"`
+}
+
+// Tells if the key belongs to the namespace or one of its sub-namespaces.
+func inNamespace(key, ns string) bool {
+	return strings.HasPrefix(key, ns+".")
+}
+
+// CheckWritable() returns an error if the key belongs to a namespace that isn't
+// writable from the command line in the show of the object. Protected
+// namespaces are allowed with protected.
+func (o *TeflonObject) CheckWritable(key string, protected bool) error {
+	if err := o.checkProtected(key, protected); err != nil {
+		return err
+	}
+	c, err := o.Config()
+	if err != nil {
+		return err
+	}
+	if c.Namespaces == nil || len(c.Namespaces.Writable) == 0 || !strings.Contains(key, ".") {
+		return nil
+	}
+	for _, ns := range c.Namespaces.Writable {
+		if inNamespace(key, ns) {
+			return nil
+		}
+	}
+	return fmt.Errorf("Key is not in a writable namespace: %s", key)
+}
+
+// Returns an error if the key belongs to a protected namespace of the show of
+// the object, unless protected is set.
+func (o *TeflonObject) checkProtected(key string, protected bool) error {
+	c, err := o.Config()
+	if err != nil {
+		return err
+	}
+	if c.Namespaces == nil || protected {
+		return nil
+	}
+	for _, ns := range c.Namespaces.Protected {
+		if inNamespace(key, ns) {
+			return fmt.Errorf("Key is in protected namespace %s: %s", ns, key)
+		}
+	}
+	return nil
+}
+
+// Returns the user metadata keys whose value, unset or inheritance state differs
+// between two metadata.
+func changedKeys(a, b *meta.PersistentMeta) []string {
+	ks := []string{}
+	for k, v := range a.UserData {
+		if w, ok := b.UserData[k]; !ok || v != w {
+			ks = append(ks, k)
+		}
+	}
+	for k := range b.UserData {
+		if _, ok := a.UserData[k]; !ok {
+			ks = append(ks, k)
+		}
+	}
+	for _, l := range [][2][]string{{a.Unset, b.Unset}, {a.NoInherit, b.NoInherit}} {
+		for _, k := range l[0] {
+			if !contains(l[1], k) {
+				ks = append(ks, k)
+			}
+		}
+		for _, k := range l[1] {
+			if !contains(l[0], k) {
+				ks = append(ks, k)
+			}
+		}
+	}
+	return ks
+}
+
+// Checks that the keys changed since old can be written. Derived fields can't
+// be written at all, protected namespaces only with WriteProtected. This check
+// is done before writing metadata to disk.
+func (o *TeflonObject) checkWrite(old *meta.PersistentMeta) error {
+	for _, k := range changedKeys(old, &o.PersistentMeta) {
+		if o.IsDerived(k) {
+			return errors.New("Derived field is read-only: " + k)
+		}
+		if err := o.checkProtected(k, WriteProtected); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sets a dotted key in a map as nested maps, so 'pipeline.publishId' becomes
+// the 'publishId' key of the 'pipeline' map. Values in the way of the nested
+// maps are replaced.
+func setNested(m map[string]interface{}, key string, v interface{}) {
+	ks := strings.Split(key, ".")
+	for _, k := range ks[:len(ks)-1] {
+		sub, ok := m[k].(map[string]interface{})
+		if !ok {
+			sub = map[string]interface{}{}
+			m[k] = sub
+		}
+		m = sub
+	}
+	m[ks[len(ks)-1]] = v
+}
+
+// Deletes a dotted key set by setNested() from a map.
+func delNested(m map[string]interface{}, key string) {
+	ks := strings.Split(key, ".")
+	for _, k := range ks[:len(ks)-1] {
+		sub, ok := m[k].(map[string]interface{})
+		if !ok {
+			return
+		}
+		m = sub
+	}
+	delete(m, ks[len(ks)-1])
+}
+
+// Looks up a dotted key set by setNested() in a map.
+func lookupNested(m map[string]interface{}, key string) (interface{}, bool) {
+	ks := strings.Split(key, ".")
+	for _, k := range ks[:len(ks)-1] {
+		sub, ok := m[k].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		m = sub
+	}
+	v, ok := m[ks[len(ks)-1]]
+	return v, ok
+}
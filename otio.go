@@ -0,0 +1,207 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// OTIO is a node of an OpenTimelineIO document. The documents are written as
+// plain JSON, nodes are maps with the 'OTIO_SCHEMA' key telling their type.
+type OTIO map[string]interface{}
+
+// Returns an OTIO node of the given schema with the fields common to all items.
+func otioItem(schema, name string) OTIO {
+	return OTIO{
+		"OTIO_SCHEMA": schema,
+		"name":        name,
+		"metadata":    map[string]interface{}{},
+		"effects":     []interface{}{},
+		"markers":     []interface{}{},
+	}
+}
+
+func otioTime(value, rate int) OTIO {
+	return OTIO{"OTIO_SCHEMA": "RationalTime.1", "value": value, "rate": rate}
+}
+
+func otioRange(start, duration, rate int) OTIO {
+	return OTIO{
+		"OTIO_SCHEMA": "TimeRange.1",
+		"start_time":  otioTime(start, rate),
+		"duration":    otioTime(duration, rate),
+	}
+}
+
+// Cut metadata of a shot needed for the timeline.
+type otioShot struct {
+	o        *TeflonObject
+	im       map[string]interface{}
+	order    int
+	cutIn    int
+	duration int
+	fps      int
+	recIn    int
+	hasRecIn bool
+}
+
+// Reads an integer metadata value.
+func otioInt(im map[string]interface{}, k string) (int, bool, error) {
+	v, ok := im[k]
+	if !ok {
+		return 0, false, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return 0, false, fmt.Errorf("Not a string: %s", k)
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false, fmt.Errorf("Not an integer: %s: %s", k, s)
+	}
+	return n, true, nil
+}
+
+// FindShots() returns the shots among the objects, or among their children if the
+// objects aren't shots themselves. Shots are the objects with a 'cutIn' key of
+// their own, inherited keys don't make their descendants shots.
+func FindShots(objs []*TeflonObject) []*TeflonObject {
+	shots := []*TeflonObject{}
+	for _, o := range objs {
+		if _, ok := o.UserData["cutIn"]; ok {
+			shots = append(shots, o)
+			continue
+		}
+		for _, ch := range o.Children() {
+			if _, ok := ch.UserData["cutIn"]; ok {
+				shots = append(shots, ch)
+			}
+		}
+	}
+	return shots
+}
+
+// CutTimeline() builds an OpenTimelineIO timeline with a single video track of
+// the shots. The shots are ordered by their 'order' key and their clips span
+// 'duration' frames from 'cutIn' at 'fps' (24 if missing). If 'duration' is
+// missing it's computed from 'cutOut'. When the shots have record timecodes in
+// 'recIn', the spaces between them become gaps and the timeline starts at the
+// record timecode of the first shot. The IMap of each shot is stored in the
+// 'teflon' metadata of its clip.
+func CutTimeline(name string, shots []*TeflonObject) (OTIO, error) {
+	if len(shots) == 0 {
+		return nil, errors.New("No shots with cut metadata.")
+	}
+
+	ss := []otioShot{}
+	for _, o := range shots {
+		s := otioShot{o: o, im: o.IMap(), fps: 24}
+		var ok bool
+		var err error
+		if s.cutIn, ok, err = otioInt(s.im, "cutIn"); err != nil || !ok {
+			return nil, fmt.Errorf("Missing cut in of %s: %v", o.Path, err)
+		}
+		if s.duration, ok, err = otioInt(s.im, "duration"); err != nil {
+			return nil, fmt.Errorf("Bad duration of %s: %v", o.Path, err)
+		}
+		if !ok {
+			out, ok, err := otioInt(s.im, "cutOut")
+			if err != nil || !ok {
+				return nil, fmt.Errorf("Missing duration and cut out of %s: %v", o.Path, err)
+			}
+			s.duration = out - s.cutIn + 1
+		}
+		if s.order, _, err = otioInt(s.im, "order"); err != nil {
+			return nil, fmt.Errorf("Bad order of %s: %v", o.Path, err)
+		}
+		if fps, ok, err := otioInt(s.im, "fps"); err != nil {
+			return nil, fmt.Errorf("Bad frame rate of %s: %v", o.Path, err)
+		} else if ok && fps > 0 {
+			s.fps = fps
+		}
+		if tc, ok := s.im["recIn"].(string); ok {
+			if s.recIn, err = TCToFrames(tc, s.fps, false); err != nil {
+				return nil, fmt.Errorf("Bad record in of %s: %v", o.Path, err)
+			}
+			s.hasRecIn = true
+		}
+		ss = append(ss, s)
+	}
+	sort.SliceStable(ss, func(i, j int) bool { return ss[i].order < ss[j].order })
+
+	rate := ss[0].fps
+	useRec := true
+	for _, s := range ss {
+		if s.fps != rate {
+			return nil, fmt.Errorf("Frame rate of %s differs: %d", s.o.Path, s.fps)
+		}
+		useRec = useRec && s.hasRecIn
+	}
+
+	children := []interface{}{}
+	pos := ss[0].recIn
+	for _, s := range ss {
+		if useRec && s.recIn > pos {
+			gap := otioItem("Gap.1", "")
+			gap["source_range"] = otioRange(0, s.recIn-pos, rate)
+			children = append(children, gap)
+			pos = s.recIn
+		}
+
+		c := otioItem("Clip.1", s.o.FileInfo.Name)
+		c["source_range"] = otioRange(s.cutIn, s.duration, rate)
+		ref := otioItem("MissingReference.1", "")
+		delete(ref, "effects")
+		delete(ref, "markers")
+		ref["available_range"] = nil
+		c["media_reference"] = ref
+		c["metadata"] = map[string]interface{}{"teflon": s.im}
+		children = append(children, c)
+		pos += s.duration
+	}
+
+	track := otioItem("Track.1", "V1")
+	track["kind"] = "Video"
+	track["source_range"] = nil
+	track["children"] = children
+
+	stack := otioItem("Stack.1", "tracks")
+	stack["source_range"] = nil
+	stack["children"] = []interface{}{track}
+
+	t := OTIO{
+		"OTIO_SCHEMA": "Timeline.1",
+		"name":        name,
+		"metadata":    map[string]interface{}{},
+		"tracks":      stack,
+	}
+	if useRec {
+		t["global_start_time"] = otioTime(ss[0].recIn, rate)
+	} else {
+		t["global_start_time"] = nil
+	}
+	return t, nil
+}
+
+// Write() writes the OTIO document as indented JSON.
+func (t OTIO) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(t)
+}
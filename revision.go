@@ -0,0 +1,109 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/gradient-images/teflon/internal/meta"
+
+	protobuf "github.com/golang/protobuf/proto"
+)
+
+// ConflictError is returned by SyncMeta() when the meta file was written by
+// someone else since the object's metadata was loaded. The in-memory changes
+// can be saved with MergeMeta().
+type ConflictError struct {
+	Path         string
+	Revision     uint64
+	DiskRevision uint64
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("Metadata of %s has changed on disk (loaded revision: %d, current revision: %d).",
+		e.Path, e.Revision, e.DiskRevision)
+}
+
+// MergeMeta() writes the changes made to the object's metadata in memory since
+// it was loaded on top of the current metadata on disk. Only the user metadata
+// keys and the fields changed by the caller are re-applied, everything else
+// comes from disk.
+func (o *TeflonObject) MergeMeta() error {
+	base := o.base
+	if base == nil {
+		base = &meta.PersistentMeta{}
+	}
+	mine := protobuf.Clone(&o.PersistentMeta).(*meta.PersistentMeta)
+
+	return o.UpdateMeta(func(o *TeflonObject) error {
+		applyChanges(&o.PersistentMeta, base, mine)
+		return nil
+	})
+}
+
+// Applies the changes between base and mine to pm.
+func applyChanges(pm, base, mine *meta.PersistentMeta) {
+	for _, ch := range diffUserData(base.UserData, mine.UserData) {
+		if ch.New == nil {
+			delete(pm.UserData, ch.Key)
+		} else {
+			pm.UserData[ch.Key] = *ch.New
+		}
+	}
+
+	pm.Unset = applyListChanges(pm.Unset, base.Unset, mine.Unset)
+	pm.NoInherit = applyListChanges(pm.NoInherit, base.NoInherit, mine.NoInherit)
+
+	if base.ShowRoot != mine.ShowRoot {
+		pm.ShowRoot = mine.ShowRoot
+	}
+	if base.ShowProto != mine.ShowProto {
+		pm.ShowProto = mine.ShowProto
+	}
+	if !reflect.DeepEqual(base.Instances, mine.Instances) {
+		pm.Instances = mine.Instances
+	}
+	if !protobuf.Equal(base.Contract, mine.Contract) {
+		pm.Contract = mine.Contract
+	}
+	if !protobuf.Equal(base.ImgInfo, mine.ImgInfo) {
+		pm.ImgInfo = mine.ImgInfo
+	}
+	if !protobuf.Equal(base.Seq, mine.Seq) {
+		pm.Seq = mine.Seq
+	}
+	if !protobuf.Equal(base.Checksum, mine.Checksum) {
+		pm.Checksum = mine.Checksum
+	}
+	if !protobuf.Equal(base.Verification, mine.Verification) {
+		pm.Verification = mine.Verification
+	}
+}
+
+// Adds the elements added and removes the elements removed between base and
+// mine to and from a string set.
+func applyListChanges(sl, base, mine []string) []string {
+	for _, s := range base {
+		if !contains(mine, s) {
+			sl = remove(sl, s)
+		}
+	}
+	for _, s := range mine {
+		if !contains(base, s) && !contains(sl, s) {
+			sl = append(sl, s)
+		}
+	}
+	return sl
+}
@@ -0,0 +1,247 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// SchemaDirName is the directory in the configuration directory holding the
+	// schemas of the show prototypes, named as '<show_proto>.json'.
+	SchemaDirName  = "schemas"
+	schemaFileName = "schema.json"
+)
+
+// Schema declares the user metadata keys of a show. A show's schema is read
+// from '.teflon/schema.json' in the show root, or if that doesn't exist, from
+// the schema of the show prototype the show was created from.
+type Schema struct {
+	// Keys not declared in Keys are rejected unless AllowUnknown is set.
+	AllowUnknown bool
+	Keys         map[string]*KeySchema
+}
+
+// KeySchema describes a single user metadata key.
+type KeySchema struct {
+	// Type is one of "string", "int", "number", "bool" or "enum". Empty means
+	// "string".
+	Type string
+	// Enum lists the allowed values of "enum" keys.
+	Enum []string
+	// Pattern is a regular expression the value has to match.
+	Pattern string
+	// Required keys have to be present on every object they apply to, either
+	// set or inherited.
+	Required bool
+	// Paths are show-absolute glob patterns (like '//sq*/sh*') of the objects
+	// the key applies to. If empty, the key applies to every object.
+	Paths []string
+}
+
+// Violation is a single schema violation found on an object.
+type Violation struct {
+	Path string
+	Key  string
+	Msg  string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s: %s", v.Path, v.Key, v.Msg)
+}
+
+// ValidationError is returned by SyncMeta() when the metadata of an object
+// doesn't conform to the schema of its show.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := []string{}
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.String())
+	}
+	return "Invalid metadata: " + strings.Join(msgs, "; ")
+}
+
+// schemas associates loaded schemas to show root paths. A nil value means the
+// show has no schema.
+var schemas = map[string]*Schema{}
+
+// LoadSchema reads a schema file.
+func LoadSchema(fspath string) (*Schema, error) {
+	in, err := ioutil.ReadFile(fspath)
+	if err != nil {
+		return nil, err
+	}
+	s := &Schema{}
+	if err = json.Unmarshal(in, s); err != nil {
+		return nil, fmt.Errorf("Couldn't parse schema %s: %v", fspath, err)
+	}
+	for k, ks := range s.Keys {
+		if ks.Pattern != "" {
+			if _, err := regexp.Compile(ks.Pattern); err != nil {
+				return nil, fmt.Errorf("Bad pattern for key '%s' in %s: %v", k, fspath, err)
+			}
+		}
+	}
+	return s, nil
+}
+
+// Schema() returns the schema of the show the object belongs to. It returns
+// nil if the object is not part of a show or the show has no schema.
+func (o *TeflonObject) Schema() (*Schema, error) {
+	if o.Show == nil {
+		return nil, nil
+	}
+	if s, ok := schemas[o.Show.Path]; ok {
+		return s, nil
+	}
+
+	files := []string{filepath.Join(o.Show.Path, teflonDirName, schemaFileName)}
+	if o.Show.ShowProto != "" {
+		files = append(files, filepath.Join(TeflonConf, SchemaDirName, o.Show.ShowProto+".json"))
+	}
+
+	var s *Schema
+	for _, f := range files {
+		if _, err := os.Stat(f); os.IsNotExist(err) {
+			continue
+		}
+		var err error
+		s, err = LoadSchema(f)
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+	schemas[o.Show.Path] = s
+	return s, nil
+}
+
+// CheckMeta() checks the user metadata set on the object itself against the
+// schema. These are the checks done before writing metadata to disk.
+func (o *TeflonObject) CheckMeta() ([]Violation, error) {
+	s, err := o.Schema()
+	if s == nil || err != nil {
+		return nil, err
+	}
+	sap := ShowAbs(o.Path)
+
+	keys := []string{}
+	for k := range o.UserData {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	vs := []Violation{}
+	for _, k := range keys {
+		ks, ok := s.Keys[k]
+		if !ok {
+			if !s.AllowUnknown {
+				vs = append(vs, Violation{sap, k, "unknown key"})
+			}
+			continue
+		}
+		if !ks.applies(sap) {
+			vs = append(vs, Violation{sap, k, "key doesn't apply to this object"})
+			continue
+		}
+		if msg := ks.check(o.UserData[k]); msg != "" {
+			vs = append(vs, Violation{sap, k, msg})
+		}
+	}
+	return vs, nil
+}
+
+// Validate() checks the object's metadata against the schema, including the
+// required keys.
+func (o *TeflonObject) Validate() ([]Violation, error) {
+	vs, err := o.CheckMeta()
+	if err != nil {
+		return nil, err
+	}
+	s, _ := o.Schema()
+	if s == nil {
+		return vs, nil
+	}
+	sap := ShowAbs(o.Path)
+
+	keys := []string{}
+	for k := range s.Keys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		ks := s.Keys[k]
+		if !ks.Required || !ks.applies(sap) {
+			continue
+		}
+		if _, _, ok := o.LookupMeta(k); !ok {
+			vs = append(vs, Violation{sap, k, "required key is missing"})
+		}
+	}
+	return vs, nil
+}
+
+// Tells if the key applies to the object at the show-absolute path.
+func (ks *KeySchema) applies(sap string) bool {
+	if len(ks.Paths) == 0 {
+		return true
+	}
+	for _, p := range ks.Paths {
+		if ok, _ := path.Match(p, sap); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks a value against the key's schema and returns a description of the
+// problem, or an empty string if the value is valid.
+func (ks *KeySchema) check(v string) string {
+	var err error
+	switch ks.Type {
+	case "", "string":
+	case "int":
+		_, err = strconv.Atoi(v)
+	case "number":
+		_, err = strconv.ParseFloat(v, 64)
+	case "bool":
+		_, err = strconv.ParseBool(v)
+	case "enum":
+		if !contains(ks.Enum, v) {
+			return fmt.Sprintf("'%s' is not one of %v", v, ks.Enum)
+		}
+	default:
+		return "unknown type in schema: " + ks.Type
+	}
+	if err != nil {
+		return fmt.Sprintf("'%s' is not of type %s", v, ks.Type)
+	}
+	if ks.Pattern != "" && !regexp.MustCompile(ks.Pattern).MatchString(v) {
+		return fmt.Sprintf("'%s' doesn't match pattern '%s'", v, ks.Pattern)
+	}
+	return ""
+}
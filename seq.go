@@ -0,0 +1,522 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gradient-images/teflon/internal/meta"
+
+	"github.com/golang/protobuf/ptypes"
+)
+
+// MinSeqFrames is the number of frames needed for files to be grouped into a
+// sequence.
+var MinSeqFrames = 2
+
+// MaxSeqGap is the largest number of consecutive missing frames in a sequence.
+// Files with larger gaps between their numbers, like dated log files, are not
+// grouped.
+var MaxSeqGap = 100
+
+// Frame files are named like 'plate.1001.exr', sequence objects like
+// 'plate.%04d.exr'. A sequence without padding is named like 'plate.%d.exr'.
+var (
+	frameRx   = regexp.MustCompile(`^(.+)\.(\d+)\.([^.]+)$`)
+	seqNameRx = regexp.MustCompile(`^(.+)\.%(?:0(\d+))?d\.([^.]+)$`)
+)
+
+// SeqName() returns the name of a sequence object.
+func SeqName(base string, padding int32, ext string) string {
+	if padding > 1 {
+		return fmt.Sprintf("%s.%%0%dd.%s", base, padding, ext)
+	}
+	return fmt.Sprintf("%s.%%d.%s", base, ext)
+}
+
+// Parses a sequence object name. Returns nil if the name is not one.
+func parseSeqName(name string) *meta.Seq {
+	m := seqNameRx.FindStringSubmatch(name)
+	if m == nil {
+		return nil
+	}
+	s := &meta.Seq{BaseName: m[1], Padding: 1, Extension: m[3]}
+	if m[2] != "" {
+		p, err := strconv.Atoi(m[2])
+		if err != nil || p < 1 {
+			return nil
+		}
+		s.Padding = int32(p)
+	}
+	return s
+}
+
+// Parses a frame file name. The returned frame number string keeps its
+// padding.
+func parseFrameName(name string) (base, num, ext string, ok bool) {
+	m := frameRx.FindStringSubmatch(name)
+	if m == nil {
+		return "", "", "", false
+	}
+	return m[1], m[2], m[3], true
+}
+
+// Tells if a frame number string is formatted with the given padding.
+func framePadded(num string, padding int32) (int, bool) {
+	n, err := strconv.Atoi(num)
+	if err != nil {
+		return 0, false
+	}
+	return n, fmt.Sprintf("%0*d", padding, n) == num
+}
+
+// Replaces the frame files in a list of file names with the names of the
+// sequences they belong to. The padding of a sequence is the length of its
+// zero padded frame numbers, or the length of its shortest frame number if
+// none of them is zero padded.
+func groupSeqs(names []string) []string {
+	type key struct{ base, ext string }
+	groups := map[key][]string{}
+	keys := []key{}
+	for _, n := range names {
+		b, num, e, ok := parseFrameName(n)
+		if !ok {
+			continue
+		}
+		k := key{b, e}
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], num)
+	}
+
+	grouped := map[string]bool{}
+	seqs := []string{}
+	for _, k := range keys {
+		nums := groups[k]
+		var padding int32
+		for _, num := range nums {
+			l := int32(len(num))
+			if num[0] == '0' && l > 1 && l > padding {
+				padding = l
+			}
+		}
+		if padding == 0 {
+			for _, num := range nums {
+				if l := int32(len(num)); padding == 0 || l < padding {
+					padding = l
+				}
+			}
+		}
+
+		members := []string{}
+		ns := []int{}
+		for _, num := range nums {
+			if n, ok := framePadded(num, padding); ok {
+				members = append(members, k.base+"."+num+"."+k.ext)
+				ns = append(ns, n)
+			}
+		}
+		sort.Ints(ns)
+		if len(members) < MinSeqFrames || seqGap(ns) > MaxSeqGap {
+			continue
+		}
+		for _, m := range members {
+			grouped[m] = true
+		}
+		seqs = append(seqs, SeqName(k.base, padding, k.ext))
+	}
+
+	res := []string{}
+	for _, n := range names {
+		if !grouped[n] {
+			res = append(res, n)
+		}
+	}
+	return append(res, seqs...)
+}
+
+// Finds the frames of a sequence in a directory. The returned frame numbers are
+// sorted.
+func scanSeq(dir string, s *meta.Seq) ([]int, []os.FileInfo, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	frames := map[int]os.FileInfo{}
+	nums := []int{}
+	for _, fi := range fis {
+		b, num, e, ok := parseFrameName(fi.Name())
+		if !ok || fi.IsDir() || b != s.BaseName || e != s.Extension {
+			continue
+		}
+		if n, ok := framePadded(num, s.Padding); ok {
+			frames[n] = fi
+			nums = append(nums, n)
+		}
+	}
+	sort.Ints(nums)
+	sfis := make([]os.FileInfo, len(nums))
+	for i, n := range nums {
+		sfis[i] = frames[n]
+	}
+	return nums, sfis, nil
+}
+
+// Initializes a sequence object from its frame files. Returns os.ErrNotExist if
+// the name is not a sequence name or there are no frames.
+func (o *TeflonObject) initSeq() error {
+	_, fis, err := readSeq(o.Path)
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	mt := fis[0].ModTime()
+	for _, fi := range fis {
+		size += fi.Size()
+		if fi.ModTime().After(mt) {
+			mt = fi.ModTime()
+		}
+	}
+	modtime, _ := ptypes.TimestampProto(mt)
+	o.FileInfo = meta.FileInfo{
+		Name:    filepath.Base(o.Path),
+		Size:    size,
+		Mode:    uint32(fis[0].Mode()),
+		ModTime: modtime,
+	}
+	o.seq = true
+	return o.loadMeta()
+}
+
+// Reads the frame range of the sequence at a file-system path. Returns
+// os.ErrNotExist if the name is not a sequence name, there are no frames or
+// the gaps between them are larger than MaxSeqGap.
+func readSeq(fspath string) (*meta.Seq, []os.FileInfo, error) {
+	s := parseSeqName(filepath.Base(fspath))
+	if s == nil {
+		return nil, nil, os.ErrNotExist
+	}
+	nums, fis, err := scanSeq(filepath.Dir(fspath), s)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(nums) == 0 || seqGap(nums) > MaxSeqGap {
+		return nil, nil, os.ErrNotExist
+	}
+
+	s.First = int32(nums[0])
+	s.Last = int32(nums[len(nums)-1])
+	for i := 1; i < len(nums); i++ {
+		for n := nums[i-1] + 1; n < nums[i]; n++ {
+			s.Missing = append(s.Missing, int32(n))
+		}
+	}
+	return s, fis, nil
+}
+
+// Returns the largest number of consecutive missing frames between sorted frame
+// numbers.
+func seqGap(nums []int) int {
+	gap := 0
+	for i := 1; i < len(nums); i++ {
+		if g := nums[i] - nums[i-1] - 1; g > gap {
+			gap = g
+		}
+	}
+	return gap
+}
+
+// Tells if a file-system path is the path of a sequence with frames on disk.
+func seqExist(fspath string) bool {
+	_, _, err := readSeq(fspath)
+	return err == nil
+}
+
+// IsSeq() tells if the object is a frame sequence.
+func (o *TeflonObject) IsSeq() bool {
+	return o.seq
+}
+
+// Returns the file name of a frame of a sequence.
+func frameName(s *meta.Seq, frame int32) string {
+	return fmt.Sprintf("%s.%0*d.%s", s.BaseName, s.Padding, frame, s.Extension)
+}
+
+// FramePath() returns the file-system path of a frame of a sequence object.
+func (o *TeflonObject) FramePath(frame int32) string {
+	return filepath.Join(filepath.Dir(o.Path), frameName(o.Seq, frame))
+}
+
+// Frames() returns the frame numbers of a sequence object that exist on disk.
+func (o *TeflonObject) Frames() []int32 {
+	frames := []int32{}
+	if !o.IsSeq() {
+		return frames
+	}
+	mi := 0
+	for n := o.Seq.First; n <= o.Seq.Last; n++ {
+		if mi < len(o.Seq.Missing) && o.Seq.Missing[mi] == n {
+			mi++
+			continue
+		}
+		frames = append(frames, n)
+	}
+	return frames
+}
+
+// Gaps() returns the ranges of missing frames of a sequence object as
+// [first, last] pairs.
+func (o *TeflonObject) Gaps() [][2]int32 {
+	gaps := [][2]int32{}
+	if !o.IsSeq() {
+		return gaps
+	}
+	for _, n := range o.Seq.Missing {
+		if l := len(gaps); l > 0 && gaps[l-1][1] == n-1 {
+			gaps[l-1][1] = n
+		} else {
+			gaps = append(gaps, [2]int32{n, n})
+		}
+	}
+	return gaps
+}
+
+// ErrNotSeq is returned by the sequence operations for objects which are not
+// frame sequences.
+var ErrNotSeq = errors.New("Not a frame sequence.")
+
+// SeqRenumber() adds offset to the frame numbers of a sequence. It returns the
+// sequence object with the updated frame range.
+func (o *TeflonObject) SeqRenumber(offset int32) (*TeflonObject, error) {
+	if !o.IsSeq() {
+		return nil, ErrNotSeq
+	}
+	return o.seqTransfer(filepath.Dir(o.Path), o.Seq.BaseName, o.Seq.Padding, offset, false)
+}
+
+// SeqPad() changes the padding of the frame numbers of a sequence. It returns
+// the sequence object under its new name.
+func (o *TeflonObject) SeqPad(padding int32) (*TeflonObject, error) {
+	if !o.IsSeq() {
+		return nil, ErrNotSeq
+	}
+	if padding < 1 {
+		padding = 1
+	}
+	return o.seqTransfer(filepath.Dir(o.Path), o.Seq.BaseName, padding, 0, false)
+}
+
+// SeqRename() changes the base name of a sequence. It returns the sequence
+// object under its new name.
+func (o *TeflonObject) SeqRename(base string) (*TeflonObject, error) {
+	if !o.IsSeq() {
+		return nil, ErrNotSeq
+	}
+	if base == "" || strings.ContainsRune(base, filepath.Separator) {
+		return nil, errors.New("Invalid base name: " + base)
+	}
+	return o.seqTransfer(filepath.Dir(o.Path), base, o.Seq.Padding, 0, false)
+}
+
+// SeqCopy() copies a sequence with the metadata of the sequence and its frames.
+// The target is either a directory, or the name of the new sequence like
+// 'dir/plate.%04d.exr'. It returns the new sequence object.
+func (o *TeflonObject) SeqCopy(target string) (*TeflonObject, error) {
+	return o.seqCopyMove(target, true)
+}
+
+// SeqMove() moves a sequence with the metadata of the sequence and its frames.
+// The target is the same as for SeqCopy(). It returns the sequence object at
+// its new place.
+func (o *TeflonObject) SeqMove(target string) (*TeflonObject, error) {
+	return o.seqCopyMove(target, false)
+}
+
+func (o *TeflonObject) seqCopyMove(target string, copy bool) (*TeflonObject, error) {
+	if !o.IsSeq() {
+		return nil, ErrNotSeq
+	}
+	fspath, err := Path(target)
+	if err != nil {
+		return nil, err
+	}
+	if IsDir(fspath) {
+		return o.seqTransfer(fspath, o.Seq.BaseName, o.Seq.Padding, 0, copy)
+	}
+	s := parseSeqName(filepath.Base(fspath))
+	if s == nil {
+		return nil, errors.New("Target is neither a directory nor a sequence name: " + target)
+	}
+	if s.Extension != o.Seq.Extension {
+		return nil, errors.New("Sequence extension can't be changed: " + target)
+	}
+	return o.seqTransfer(filepath.Dir(fspath), s.BaseName, s.Padding, 0, copy)
+}
+
+// Copies or moves the frames of a sequence to a directory under a new name
+// layout and frame numbers. The meta files of the sequence and the frames go
+// with them. When frames are renumbered in place, they are processed in an
+// order that never overwrites a frame which is yet to be moved.
+func (o *TeflonObject) seqTransfer(dir, base string, padding, offset int32, copy bool) (*TeflonObject, error) {
+	if !IsDir(dir) {
+		return nil, errors.New("Target directory doesn't exist: " + dir)
+	}
+	// Padding shorter than the first frame number is meaningless, it's set to
+	// the length of the first frame number, as in groupSeqs().
+	if l := int32(len(strconv.Itoa(int(o.Seq.First + offset)))); l > padding {
+		padding = l
+	}
+	ns := &meta.Seq{BaseName: base, Padding: padding, Extension: o.Seq.Extension}
+	np := filepath.Join(dir, SeqName(base, padding, ns.Extension))
+	if np == o.Path && offset == 0 {
+		return o, nil
+	}
+
+	l, err := o.lockMeta()
+	if err != nil {
+		return nil, err
+	}
+	err = o.transferFrames(dir, ns, offset, copy)
+	l.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := NewTeflonObject(np)
+	if err != nil {
+		return nil, err
+	}
+	// Record the new frame range.
+	if err := n.UpdateMeta(func(*TeflonObject) error { return nil }); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Does the file operations of seqTransfer(). The caller has to hold the lock
+// of the sequence's metadata.
+func (o *TeflonObject) transferFrames(dir string, ns *meta.Seq, offset int32, copy bool) error {
+	np := filepath.Join(dir, SeqName(ns.BaseName, ns.Padding, ns.Extension))
+	d, err := NewTeflonObject(dir)
+	if err != nil {
+		return err
+	}
+
+	// Plan the transfers and check for collisions.
+	frames := o.Frames()
+	if !copy && offset > 0 {
+		for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+			frames[i], frames[j] = frames[j], frames[i]
+		}
+	}
+	srcs := map[string]bool{}
+	for _, n := range frames {
+		srcs[o.FramePath(n)] = true
+	}
+	type transfer struct{ src, dst string }
+	plan := []transfer{}
+	for _, n := range frames {
+		if n+offset < 0 {
+			return fmt.Errorf("Negative frame number: %d", n+offset)
+		}
+		t := transfer{o.FramePath(n), filepath.Join(dir, frameName(ns, n+offset))}
+		if Exist(t.dst) && (copy || !srcs[t.dst]) {
+			return errors.New("Target already exists: " + t.dst)
+		}
+		if t.src == t.dst {
+			continue
+		}
+		plan = append(plan, t)
+	}
+	nm := &TeflonObject{Path: np, Show: d.Show}
+	if np != o.Path && nm.HasMeta() {
+		return errors.New("Target already has metadata: " + np)
+	}
+
+	// Forget the objects in memory that won't reflect the disk anymore.
+	defer func() {
+		forget(o.Path)
+		forget(np)
+		for _, t := range plan {
+			forget(t.src)
+			forget(t.dst)
+		}
+	}()
+
+	for _, t := range plan {
+		var err error
+		if copy {
+			err = copyFile(t.src, t.dst)
+		} else {
+			err = os.Rename(t.src, t.dst)
+		}
+		if err != nil {
+			return err
+		}
+		err = transferMeta(&TeflonObject{Path: t.src, Show: o.Show},
+			&TeflonObject{Path: t.dst, Show: d.Show}, copy)
+		if err != nil {
+			return err
+		}
+	}
+	if np != o.Path {
+		return transferMeta(o, nm, copy)
+	}
+	return nil
+}
+
+// Copies or moves the stored metadata of an object to another one as it is.
+// Metadata that moved with its file, like extended attributes, is left alone,
+// just like the metadata of an object transferred onto itself.
+func transferMeta(src, dst *TeflonObject, copy bool) error {
+	if src.Path == dst.Path {
+		return nil
+	}
+	ss, err := src.MetaStore()
+	if err != nil {
+		return err
+	}
+	ds, err := dst.MetaStore()
+	if err != nil {
+		return err
+	}
+	if !ss.Has(src) {
+		return nil
+	}
+	pm := &meta.PersistentMeta{}
+	if err := ss.Read(src, pm); err != nil {
+		return err
+	}
+	c, err := dst.Config()
+	if err != nil {
+		return err
+	}
+	if err := ds.Write(dst, pm, c.MetaFormat); err != nil {
+		return err
+	}
+	if copy {
+		return nil
+	}
+	return ss.Delete(src)
+}
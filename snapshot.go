@@ -0,0 +1,303 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/gradient-images/teflon/internal/meta"
+
+	"github.com/golang/protobuf/jsonpb"
+	protobuf "github.com/golang/protobuf/proto"
+)
+
+// SnapshotExtension is the extension of snapshot files.
+const SnapshotExtension = ".tsnap"
+
+// Snapshot is the metadata of a subtree of objects at a point in time, without
+// the media. Objects are stored by their path relative to the root of the
+// subtree, the root itself as '.'. Only objects with stored metadata are kept.
+type Snapshot struct {
+	// Root is the show-absolute path of the subtree.
+	Root    string
+	Time    time.Time
+	User    string
+	Objects map[string]*meta.PersistentMeta
+}
+
+// The form snapshots are stored in. Metadata is stored as canonical JSON.
+type snapshotFile struct {
+	Root    string
+	Time    time.Time
+	User    string
+	Objects map[string]json.RawMessage
+}
+
+// Snapshot() takes a snapshot of the metadata of the object and all of its
+// descendants.
+func (o *TeflonObject) Snapshot() (*Snapshot, error) {
+	s := &Snapshot{
+		Root:    ShowAbs(o.Path),
+		Time:    time.Now(),
+		User:    currentUser(),
+		Objects: map[string]*meta.PersistentMeta{},
+	}
+	add := func(d *TeflonObject) error {
+		if !d.HasMeta() {
+			return nil
+		}
+		rel, err := filepath.Rel(o.Path, d.Path)
+		if err != nil {
+			return err
+		}
+		s.Objects[rel] = protobuf.Clone(&d.PersistentMeta).(*meta.PersistentMeta)
+		return nil
+	}
+	// Frames of sequences are not walked, but they have their own metadata.
+	err := o.Walk(func(d *TeflonObject) error {
+		if err := add(d); err != nil {
+			return err
+		}
+		for _, n := range d.Frames() {
+			f, err := NewTeflonObject(d.FramePath(n))
+			if err != nil {
+				return err
+			}
+			if err := add(f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Write() writes the snapshot to a gzip compressed JSON file.
+func (s *Snapshot) Write(fspath string) error {
+	sf := snapshotFile{Root: s.Root, Time: s.Time, User: s.User, Objects: map[string]json.RawMessage{}}
+	for rel, pm := range s.Objects {
+		j, err := canonicalJSON(pm)
+		if err != nil {
+			return err
+		}
+		sf.Objects[rel] = j
+	}
+	j, err := json.MarshalIndent(sf, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	b := &bytes.Buffer{}
+	zw := gzip.NewWriter(b)
+	if _, err := zw.Write(append(j, '\n')); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return writeFileAtomic(fspath, b.Bytes(), 0644)
+}
+
+// ReadSnapshot() reads a snapshot file.
+func ReadSnapshot(fspath string) (*Snapshot, error) {
+	f, err := os.Open(fspath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	sf := snapshotFile{}
+	if err := json.NewDecoder(zr).Decode(&sf); err != nil {
+		return nil, err
+	}
+	s := &Snapshot{Root: sf.Root, Time: sf.Time, User: sf.User, Objects: map[string]*meta.PersistentMeta{}}
+	for rel, j := range sf.Objects {
+		pm := &meta.PersistentMeta{}
+		if err := jsonpb.Unmarshal(bytes.NewReader(j), pm); err != nil {
+			return nil, fmt.Errorf("Couldn't read metadata of %s: %v", rel, err)
+		}
+		if err := migrateMeta(pm); err != nil {
+			return nil, err
+		}
+		s.Objects[rel] = pm
+	}
+	return s, nil
+}
+
+// ObjectDiff is the difference of the metadata of an object between two
+// states. Status is '+' for added, '-' for removed and '~' for changed objects.
+type ObjectDiff struct {
+	Path    string
+	Status  string
+	Changes []KeyDiff
+}
+
+func (d ObjectDiff) String() string {
+	s := d.Status + " " + d.Path
+	for _, ch := range d.Changes {
+		s += "\n    " + ch.String()
+	}
+	return s
+}
+
+// Fields that change with every write and are left out of snapshot diffs.
+var snapshotVolatile = []string{"Revision", "Version"}
+
+// Converts metadata to a map for DiffMaps().
+func metaMap(pm *meta.PersistentMeta) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	if pm == nil {
+		return m, nil
+	}
+	j, err := canonicalJSON(pm)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(j, &m); err != nil {
+		return nil, err
+	}
+	for _, k := range snapshotVolatile {
+		delete(m, k)
+	}
+	// Empty lists and maps are the same as unset ones in protobuf.
+	for k, v := range m {
+		switch v := v.(type) {
+		case []interface{}:
+			if len(v) == 0 {
+				delete(m, k)
+			}
+		case map[string]interface{}:
+			if len(v) == 0 {
+				delete(m, k)
+			}
+		}
+	}
+	return m, nil
+}
+
+// DiffSnapshots() compares two snapshots object by object. Objects are matched
+// by their relative paths. Unchanged objects are left out, the result is sorted
+// by path.
+func DiffSnapshots(a, b *Snapshot) ([]ObjectDiff, error) {
+	paths := map[string]bool{}
+	for p := range a.Objects {
+		paths[p] = true
+	}
+	for p := range b.Objects {
+		paths[p] = true
+	}
+
+	ds := []ObjectDiff{}
+	for p := range paths {
+		am, err := metaMap(a.Objects[p])
+		if err != nil {
+			return nil, err
+		}
+		bm, err := metaMap(b.Objects[p])
+		if err != nil {
+			return nil, err
+		}
+		d := ObjectDiff{Path: p, Status: "~", Changes: DiffMaps(am, bm)}
+		switch {
+		case a.Objects[p] == nil:
+			d.Status = "+"
+		case b.Objects[p] == nil:
+			d.Status = "-"
+		case len(d.Changes) == 0:
+			continue
+		}
+		ds = append(ds, d)
+	}
+	sort.Slice(ds, func(i, j int) bool { return ds[i].Path < ds[j].Path })
+	return ds, nil
+}
+
+// Restore() puts the metadata of the snapshot back onto the objects under the
+// object, which is usually the root of the snapshot. Only objects whose
+// metadata differs from the snapshot are written, their revision keeps
+// counting up. Objects that had no metadata in the snapshot get their metadata
+// cleared. Sequence ranges are always taken from the disk. Objects that don't
+// exist anymore are skipped with a warning. Restoring is an ordinary write, so
+// keys in protected namespaces need WriteProtected. The objects' differences
+// from the snapshot are returned, with dryRun nothing is written.
+func (s *Snapshot) Restore(o *TeflonObject, dryRun bool) ([]ObjectDiff, error) {
+	cur, err := o.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	ds, err := DiffSnapshots(cur, s)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []ObjectDiff{}
+	for _, d := range ds {
+		pm := s.Objects[d.Path]
+		if d.Status == "-" {
+			// Cleared metadata is still stored, so it's only cleared once.
+			pm = &meta.PersistentMeta{Seq: cur.Objects[d.Path].Seq}
+			cm, err := metaMap(cur.Objects[d.Path])
+			if err != nil {
+				return nil, err
+			}
+			pmm, err := metaMap(pm)
+			if err != nil {
+				return nil, err
+			}
+			if d.Changes = DiffMaps(cm, pmm); len(d.Changes) == 0 {
+				continue
+			}
+		}
+		fspath := filepath.Join(o.Path, d.Path)
+		if !Exist(fspath) && !seqExist(fspath) {
+			log.Println("WARNING: Object doesn't exist anymore, skipping:", fspath)
+			continue
+		}
+		res = append(res, d)
+		if dryRun {
+			continue
+		}
+
+		t, err := NewTeflonObject(fspath)
+		if err != nil {
+			return nil, err
+		}
+		err = t.UpdateMeta(func(t *TeflonObject) error {
+			pm := protobuf.Clone(pm).(*meta.PersistentMeta)
+			pm.Revision, pm.Version, pm.Seq = t.Revision, t.Version, t.Seq
+			t.PersistentMeta = *pm
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,332 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PathColumn is the name of the column holding the paths of the objects in
+// exported and imported tables.
+const PathColumn = "Path"
+
+// Table is a list of objects with some of their metadata, the way spreadsheets
+// hold them. Exported tables start with the PathColumn.
+type Table struct {
+	Columns []string
+	Rows    [][]string
+}
+
+// ExportTable() builds a table of the objects with the given keys as columns.
+// Keys are looked up in the objects' IMap, so inherited user metadata and
+// nested keys like 'ImgInfo.Width' can be exported too. Without keys the user
+// metadata keys of all the objects are exported. Paths are show-absolute.
+func ExportTable(objs []*TeflonObject, keys []string) (*Table, error) {
+	if len(keys) == 0 {
+		ks := map[string]bool{}
+		for _, o := range objs {
+			for k := range o.InheritedMeta() {
+				ks[k] = true
+			}
+		}
+		for k := range ks {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+	}
+
+	t := &Table{Columns: append([]string{PathColumn}, keys...)}
+	for _, o := range objs {
+		im := o.IMap()
+		row := []string{ShowAbs(o.Path)}
+		for _, k := range keys {
+			v, ok := lookupNested(im, k)
+			if !ok {
+				if ex, err := NewExpr(k + "@"); err == nil && ex.MetaSelector != nil {
+					v, _ = ex.MetaSelector.Eval(&Context{Dir: o, IMap: im})
+				}
+			}
+			c, err := cellValue(v)
+			if err != nil {
+				return nil, err
+			}
+			row = append(row, c)
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t, nil
+}
+
+// Converts a metadata value to a table cell. Strings are kept as they are, other
+// values are JSON encoded.
+func cellValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+	j, err := json.Marshal(v)
+	return string(j), err
+}
+
+// WriteCSV() writes the table as CSV with a header row.
+func (t *Table) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Columns); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(t.Rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// WriteJSON() writes the table as a JSON array of objects, one for each row.
+// Empty cells are left out.
+func (t *Table) WriteJSON(w io.Writer) error {
+	objs := []map[string]string{}
+	for _, row := range t.Rows {
+		m := map[string]string{}
+		for i, c := range row {
+			if c != "" {
+				m[t.Columns[i]] = c
+			}
+		}
+		objs = append(objs, m)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(objs)
+}
+
+// ReadCSV() reads a table from CSV with a header row.
+func ReadCSV(r io.Reader) (*Table, error) {
+	recs, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(recs) == 0 {
+		return nil, errors.New("Missing header row.")
+	}
+	for i, c := range recs[0] {
+		recs[0][i] = strings.TrimSpace(c)
+	}
+	return &Table{Columns: recs[0], Rows: recs[1:]}, nil
+}
+
+// ReadJSON() reads a table from a JSON array of objects. The columns are the
+// keys of all the objects, in alphabetical order.
+func ReadJSON(r io.Reader) (*Table, error) {
+	objs := []map[string]interface{}{}
+	if err := json.NewDecoder(r).Decode(&objs); err != nil {
+		return nil, err
+	}
+	ks := map[string]bool{}
+	for _, o := range objs {
+		for k := range o {
+			ks[k] = true
+		}
+	}
+	t := &Table{}
+	for k := range ks {
+		t.Columns = append(t.Columns, k)
+	}
+	sort.Strings(t.Columns)
+
+	for _, o := range objs {
+		row := []string{}
+		for _, k := range t.Columns {
+			c, err := cellValue(o[k])
+			if err != nil {
+				return nil, err
+			}
+			row = append(row, c)
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t, nil
+}
+
+// Returns the index of a column, or -1 if the table doesn't have it.
+func (t *Table) column(name string) int {
+	for i, c := range t.Columns {
+		if c == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// ImportPlan is the change that importing a table row makes on an object.
+type ImportPlan struct {
+	Path    string
+	Create  bool
+	Changes []MetaChange
+}
+
+func (p ImportPlan) String() string {
+	s := "~ " + p.Path
+	if p.Create {
+		s = "+ " + p.Path
+	}
+	for _, ch := range p.Changes {
+		s += fmt.Sprintf("\n    %s: %s -> %s", ch.Key, planValue(ch.Old), planValue(ch.New))
+	}
+	return s
+}
+
+func planValue(v *string) string {
+	if v == nil {
+		return "<none>"
+	}
+	return fmt.Sprintf("%q", *v)
+}
+
+// PlanImport() matches the rows of a table to objects and computes the changes
+// of their user metadata, without writing anything. Rows are matched by the
+// value of keyCol among the user metadata of the object and its descendants, or
+// by the PathColumn if keyCol is empty or no object has the value. Paths are
+// relative to the object unless they're absolute. Rows with paths of
+// non-existent objects are planned for creation. Cells are compared to the
+// inherited metadata, like ExportTable() exports it, so cells equal to the
+// inherited values don't change anything, just like empty cells. Rows that
+// can't be matched are skipped with a warning.
+func (o *TeflonObject) PlanImport(t *Table, keyCol string) ([]ImportPlan, error) {
+	pc := t.column(PathColumn)
+	kc := -1
+	byKey := map[string]*TeflonObject{}
+	if keyCol != "" {
+		if kc = t.column(keyCol); kc < 0 {
+			return nil, errors.New("Missing key column: " + keyCol)
+		}
+		err := o.Walk(func(d *TeflonObject) error {
+			if v, ok := d.UserData[keyCol]; ok {
+				byKey[v] = d
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else if pc < 0 {
+		return nil, errors.New("Missing column: " + PathColumn)
+	}
+
+	plans := []ImportPlan{}
+	for i, row := range t.Rows {
+		if len(row) != len(t.Columns) {
+			return nil, fmt.Errorf("Row %d has %d cells instead of %d.", i+1, len(row), len(t.Columns))
+		}
+
+		var target *TeflonObject
+		fspath := ""
+		if kc >= 0 {
+			target = byKey[row[kc]]
+		}
+		if target == nil && pc >= 0 && row[pc] != "" {
+			var err error
+			if fspath, err = o.resolve(row[pc]); err != nil {
+				return nil, err
+			}
+			if Exist(fspath) || seqExist(fspath) {
+				if target, err = NewTeflonObject(fspath); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if target == nil && fspath == "" {
+			log.Printf("WARNING: Couldn't match row %d, skipping.", i+1)
+			continue
+		}
+
+		p := ImportPlan{Path: fspath, Create: target == nil}
+		if target != nil {
+			p.Path = target.Path
+		} else if parent, err := NewTeflonObject(filepath.Dir(fspath)); err == nil {
+			target = &TeflonObject{Path: fspath, Parent: parent, Show: parent.Show}
+		}
+		for ci, c := range row {
+			if ci == pc || c == "" {
+				continue
+			}
+			k := t.Columns[ci]
+			var ov string
+			var ok bool
+			if target != nil {
+				ov, _, ok = target.LookupMeta(k)
+			}
+			if ok && ov == c {
+				continue
+			}
+			nv := c
+			ch := MetaChange{Key: k, New: &nv}
+			if ok {
+				ch.Old = &ov
+			}
+			p.Changes = append(p.Changes, ch)
+		}
+		if p.Create || len(p.Changes) > 0 {
+			plans = append(plans, p)
+		}
+	}
+	return plans, nil
+}
+
+// Converts a path relative to the object to a file-system path.
+func (o *TeflonObject) resolve(target string) (string, error) {
+	if strings.HasPrefix(target, "/") {
+		return Path(target)
+	}
+	return filepath.Join(o.Path, target), nil
+}
+
+// ApplyImport() carries out import plans. Objects are created by their literal
+// paths, triggering the same events as CreateObject(), as directories unless
+// file is set, and their metadata is set in a single update each.
+func ApplyImport(plans []ImportPlan, file bool) error {
+	for _, p := range plans {
+		if p.Create {
+			parent, err := NewTeflonObject(filepath.Dir(p.Path))
+			if err != nil {
+				return err
+			}
+			if _, err := parent.createPath(p.Path, file); err != nil {
+				return err
+			}
+		}
+		o, err := NewTeflonObject(p.Path)
+		if err != nil {
+			return err
+		}
+		err = o.UpdateMeta(func(o *TeflonObject) error {
+			for _, ch := range p.Changes {
+				o.SetMeta(ch.Key, *ch.New)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/gradient-images/teflon"
+
+	"github.com/spf13/cobra"
+)
+
+var aleIntoFlag string
+
+var importALECmd = &cobra.Command{
+	Use:   "import-ale [-n] [--into <target>] <ale>",
+	Short: "Imports ALE columns into the metadata of plates",
+	Long: `'teflon import-ale' reads a tab delimited Avid Log Exchange (ALE) file and
+sets the user metadata of the objects under <target> that its rows match.
+
+Rows are matched by the 'Source File' column to the name of the objects, then by
+the 'Name' column to the 'clip' key or the name of the objects, and finally by
+the 'Tape' column to the 'reel' key. Extensions are ignored when comparing, and
+sequences are matched by their base name. A row can match several plates.
+
+By default all columns are imported with keys made of their names, like
+'sourceFile' from 'Source File'. The columns to import, their keys and the
+matching rules can be set in the 'ALE' section of the show config:
+
+  "ALE": {
+    "Columns": {"Name": "clip", "Camera": "camera", "ISO": "iso"},
+    "Match": [{"Column": "Name", "Key": "clip"}, {"Column": "Name"}]
+  }
+
+The planned changes are printed. With '-n' nothing is written.`,
+	Args: cobra.ExactArgs(1),
+	Run:  ImportALE,
+}
+
+func init() {
+	importALECmd.Flags().StringVar(&aleIntoFlag, "into", ".",
+		"Object to search the plates under.")
+	importALECmd.Flags().BoolVarP(&dryRunFlag, "dry-run", "n", false,
+		"Only print the changes.")
+	rootCmd.AddCommand(importALECmd)
+}
+
+// ImportALE() or `teflon import-ale` imports ALE metadata.
+func ImportALE(cmd *cobra.Command, args []string) {
+	f, err := os.Open(args[0])
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't open ALE:", err)
+	}
+	defer f.Close()
+
+	ale, err := teflon.ParseALE(f)
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't parse ALE:", err)
+	}
+
+	into, err := teflon.NewTeflonObject(aleIntoFlag)
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't create object:", err)
+	}
+	plans, err := into.PlanALE(ale)
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't match ALE:", err)
+	}
+
+	for _, p := range plans {
+		fmt.Println(p)
+	}
+	if dryRunFlag {
+		log.Printf("SUCCESS: Dry run, %d objects would change.", len(plans))
+		return
+	}
+	if err := teflon.ApplyImport(plans, false); err != nil {
+		log.Fatalln("ABORT: Couldn't import:", err)
+	}
+	log.Printf("SUCCESS: Updated %d objects.", len(plans))
+}
@@ -0,0 +1,119 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gradient-images/teflon"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	checksumForceFlag bool
+	verifyAllFlag     bool
+)
+
+var checksumCmd = &cobra.Command{
+	Use:   "checksum [-f] <expr>",
+	Short: "Computes checksums of files",
+	Long: `'teflon checksum' computes the SHA-256 and xxHash64 checksums of the files
+selected by <expr> and their descendants, and stores them in the Checksum
+section of their metadata, next to FileInfo. Sequences are hashed frame by
+frame. Files whose size and modification time didn't change since they were last
+hashed are skipped, unless '-f' is given.`,
+	Args: cobra.ExactArgs(1),
+	Run:  Checksum,
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [-a] [<expr>]",
+	Short: "Verifies the checksums of files",
+	Long: `'teflon verify' re-hashes the files selected by <expr> and their descendants,
+and compares the results with the stored checksums. Problems are reported one
+per line with one of the statuses:
+
+  mismatch  the file has changed since it was hashed
+  missing   the file was hashed but doesn't exist anymore
+  new       the file was never hashed
+
+With '-a' the verified files are listed too. If no <expr> is given '.' is
+verified. The command exits with a non-zero status if there are mismatching or
+missing files.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  Verify,
+}
+
+func init() {
+	checksumCmd.Flags().BoolVarP(&checksumForceFlag, "force", "f", false,
+		"Re-hash unchanged files too.")
+	verifyCmd.Flags().BoolVarP(&verifyAllFlag, "all", "a", false,
+		"List the verified files too.")
+	rootCmd.AddCommand(checksumCmd)
+	rootCmd.AddCommand(verifyCmd)
+}
+
+// Checksum() or `teflon checksum` stores checksums in metadata.
+func Checksum(cmd *cobra.Command, args []string) {
+	hashed, skipped := 0, 0
+	for _, o := range findObjects(args[0]) {
+		err := o.WalkFiles(func(f *teflon.TeflonObject) error {
+			ok, err := f.UpdateChecksum(checksumForceFlag)
+			if err != nil {
+				return err
+			}
+			if ok {
+				hashed++
+				fmt.Printf("%s  %s\n", f.Checksum.SHA256, f.Path)
+			} else {
+				skipped++
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatalln("ABORT: Couldn't compute checksum:", err)
+		}
+	}
+	log.Printf("SUCCESS: Hashed %d files, skipped %d unchanged files.", hashed, skipped)
+}
+
+// Verify() or `teflon verify` checks files against their stored checksums.
+func Verify(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		args = append(args, ".")
+	}
+
+	count := map[string]int{}
+	for _, o := range findObjects(args[0]) {
+		rs, err := o.Verify()
+		if err != nil {
+			log.Fatalln("ABORT: Couldn't verify:", err)
+		}
+		for _, r := range rs {
+			count[r.Status]++
+			if r.Status != teflon.VerifyOK || verifyAllFlag {
+				fmt.Println(r)
+			}
+		}
+	}
+
+	msg := fmt.Sprintf("%d ok, %d mismatch, %d missing, %d new.", count[teflon.VerifyOK],
+		count[teflon.VerifyMismatch], count[teflon.VerifyMissing], count[teflon.VerifyNew])
+	if count[teflon.VerifyMismatch] > 0 || count[teflon.VerifyMissing] > 0 {
+		log.Fatalln("ABORT: Verification failed:", msg)
+	}
+	log.Println("SUCCESS: Verified:", msg)
+}
@@ -23,14 +23,23 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var contractPatternFlag string
+var (
+	contractPatternFlag string
+	contractDirsFlag    []string
+)
 
 var contractCmd = &cobra.Command{
-	Use:   "contract [-p <pattern>] [<expr>]",
+	Use:   "contract [-p <pattern>] [-d <dirs>] [<expr>]",
 	Short: "Manipulates contracts",
-	Long:  `'teflon contract' sets and gets contract related system metadata on contract objects.`,
-	Args:  cobra.ExactArgs(1),
-	Run:   Contract,
+	Long: `'teflon contract' sets and gets contract related system metadata on contract objects.
+
+Contract objects live in the '.teflon/contract' directory of an object. When a
+new object is created under the object and the Pattern of a contract selects
+it, the contract is executed: the content of the contract object is copied into
+the new object, the user metadata of the contract object is set on it, and the
+directories given by '-d' are created in it.`,
+	Args: cobra.ExactArgs(1),
+	Run:  Contract,
 }
 
 func init() {
@@ -40,6 +49,12 @@ func init() {
 		"p",
 		"",
 		"Set the contracts Pattern value to the given object selector expression.")
+	contractCmd.Flags().StringSliceVarP(
+		&contractDirsFlag,
+		"dirs",
+		"d",
+		[]string{},
+		"Comma separated list of directories the contracts create.")
 	rootCmd.AddCommand(contractCmd)
 }
 
@@ -63,6 +78,14 @@ func Contract(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	if cmd.Flags().Changed("dirs") {
+		log.Println("DEBUG: Setting directories for contracts.")
+		res, err = pwd.SetContractDirs(args[0], contractDirsFlag)
+		if err != nil {
+			log.Fatalln("ABORT: Couldn't set contract:", err)
+		}
+	}
+
 	// Create display string of result (dres).
 	dres, err := json.MarshalIndent(res, "", "  ")
 	if err != nil {
@@ -0,0 +1,90 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/gradient-images/teflon"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffFormatFlag string
+	diffAllFlag    bool
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [-a] [--format text|json] <exprA> <exprB>",
+	Short: "Compares the metadata of two objects",
+	Long: `'teflon diff' compares the metadata of the objects selected by <exprA> and
+<exprB>, like a shot and its prototype, or two versions of a plate. Both
+expressions have to select a single object. The comparison is done key by key on
+the objects' IMap, so inherited metadata counts too. Nested keys are dotted,
+like 'ImgInfo.Width'.
+
+In text format added keys are marked with '+', removed keys with '-' and changed
+keys with '~'. The keys identifying the objects, like Path and FileInfo, are
+left out unless '-a' is given.`,
+	Args: cobra.ExactArgs(2),
+	Run:  Diff,
+}
+
+func init() {
+	diffCmd.Flags().StringVar(&diffFormatFlag, "format", "text",
+		"Output format, 'text' or 'json'.")
+	diffCmd.Flags().BoolVarP(&diffAllFlag, "all", "a", false,
+		"Compare the identifying keys too.")
+	rootCmd.AddCommand(diffCmd)
+}
+
+// Diff() or `teflon diff` compares objects.
+func Diff(cmd *cobra.Command, args []string) {
+	objs := []*teflon.TeflonObject{}
+	for _, a := range args {
+		found := findObjects(a)
+		if len(found) != 1 {
+			log.Fatalf("ABORT: Expression selects %d objects instead of one: %s", len(found), a)
+		}
+		objs = append(objs, found[0])
+	}
+	ds := objs[0].Diff(objs[1], diffAllFlag)
+
+	switch diffFormatFlag {
+	case "text":
+		fmt.Println("---", teflon.ShowAbs(objs[0].Path))
+		fmt.Println("+++", teflon.ShowAbs(objs[1].Path))
+		for _, d := range ds {
+			fmt.Println(d)
+		}
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err := enc.Encode(map[string]interface{}{
+			"A":       teflon.ShowAbs(objs[0].Path),
+			"B":       teflon.ShowAbs(objs[1].Path),
+			"Changes": ds,
+		})
+		if err != nil {
+			log.Fatalln("ABORT: Couldn't write diff:", err)
+		}
+	default:
+		log.Fatalln("ABORT: Unknown format:", diffFormatFlag)
+	}
+	log.Printf("SUCCESS: %d keys differ.", len(ds))
+}
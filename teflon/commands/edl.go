@@ -0,0 +1,111 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/gradient-images/teflon"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	edlIntoFlag    string
+	edlFPSFlag     int
+	edlHandlesFlag int
+	edlStartFlag   int
+	edlPrefixFlag  string
+)
+
+var importEDLCmd = &cobra.Command{
+	Use:   "import-edl [-n] --into <target> <edl>",
+	Short: "Creates shots from a CMX3600 EDL",
+	Long: `'teflon import-edl' reads the video events of a CMX3600 EDL and creates a shot
+under <target> for each of them, or updates the shots that already exist. Shots
+are named after the '* LOC:' comment of their event, or by their position in
+the cut, like 'sh010', 'sh020'. Existing shots without a '* LOC:' comment keep
+their names, they are matched by their reel, clip and source timecodes.
+
+The cut metadata of the shots are: event, reel, clip, srcIn, srcOut, recIn,
+recOut, duration, handles, cutIn, cutOut, headIn, tailOut, order and fps. The
+first frame of the shots including handles is given by '--start'.
+
+The added, retimed and removed shots are reported. Removed shots are left
+untouched. With '-n' nothing is written.`,
+	Args: cobra.ExactArgs(1),
+	Run:  ImportEDL,
+}
+
+func init() {
+	importEDLCmd.Flags().StringVar(&edlIntoFlag, "into", ".",
+		"Object to create the shots under.")
+	importEDLCmd.Flags().IntVar(&edlFPSFlag, "fps", 24,
+		"Frame rate of the timecodes.")
+	importEDLCmd.Flags().IntVar(&edlHandlesFlag, "handles", 8,
+		"Number of handle frames at both ends of the shots.")
+	importEDLCmd.Flags().IntVar(&edlStartFlag, "start", 1001,
+		"First frame of the shots including handles.")
+	importEDLCmd.Flags().StringVar(&edlPrefixFlag, "prefix", "sh",
+		"Prefix of generated shot names.")
+	importEDLCmd.Flags().BoolVarP(&dryRunFlag, "dry-run", "n", false,
+		"Only report the changes.")
+	rootCmd.AddCommand(importEDLCmd)
+}
+
+// ImportEDL() or `teflon import-edl` creates shots from an EDL.
+func ImportEDL(cmd *cobra.Command, args []string) {
+	f, err := os.Open(args[0])
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't open EDL:", err)
+	}
+	defer f.Close()
+
+	edl, err := teflon.ParseEDL(f)
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't parse EDL:", err)
+	}
+	shots, err := edl.Shots(teflon.CutOptions{
+		FPS:     edlFPSFlag,
+		Handles: edlHandlesFlag,
+		Start:   edlStartFlag,
+		Prefix:  edlPrefixFlag,
+	})
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't convert events to shots:", err)
+	}
+
+	into, err := teflon.NewTeflonObject(edlIntoFlag)
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't create object:", err)
+	}
+	d, err := into.ImportCut(shots, dryRunFlag)
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't import cut:", err)
+	}
+
+	for _, n := range d.Added {
+		fmt.Println("added   ", n)
+	}
+	for _, n := range d.Retimed {
+		fmt.Println("retimed ", n)
+	}
+	for _, n := range d.Removed {
+		fmt.Println("removed ", n)
+	}
+	log.Printf("SUCCESS: %d added, %d retimed, %d removed, %d unchanged shots.",
+		len(d.Added), len(d.Retimed), len(d.Removed), len(d.Unchanged))
+}
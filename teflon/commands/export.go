@@ -0,0 +1,163 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gradient-images/teflon"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormatFlag string
+	importFormatFlag string
+	tableKeysFlag    []string
+	tableOutputFlag  string
+	importKeyFlag    string
+	importFileFlag   bool
+	dryRunFlag       bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [-k key,..] [--format csv|json] [-o <file>] <expr>",
+	Short: "Exports metadata as a table",
+	Long: `'teflon export' writes one row for each object selected by <expr>, with the
+show-absolute path of the object in the 'Path' column and the keys given by '-k'
+in the others. Keys can be inherited user metadata or nested keys like
+'ImgInfo.Width'. Without '-k' all the user metadata keys of the objects are
+exported. The table is written to the standard output unless '-o' is given, as
+CSV or as a JSON array of objects.`,
+	Args: cobra.ExactArgs(1),
+	Run:  Export,
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import [-k <key>] [-n] [--format csv|json] <file>",
+	Short: "Imports metadata from a table",
+	Long: `'teflon import' sets user metadata from a CSV or JSON table, like the ones
+written by 'teflon export'. Rows are matched to objects by the 'Path' column, or
+by the value of the key given by '-k' among the objects under '.'. Paths are
+relative to '.' unless they're absolute. Objects that don't exist are created,
+as directories unless '--file' is given. Empty cells don't change anything.
+
+The changes are printed before anything is written. With '-n' nothing is
+written. The format is taken from the file extension unless '--format' is
+given.`,
+	Args: cobra.ExactArgs(1),
+	Run:  Import,
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportFormatFlag, "format", "csv",
+		"Table format, 'csv' or 'json'.")
+	exportCmd.Flags().StringSliceVarP(&tableKeysFlag, "keys", "k", []string{},
+		"Comma separated list of keys to export.")
+	exportCmd.Flags().StringVarP(&tableOutputFlag, "output", "o", "",
+		"File to write the table to.")
+	importCmd.Flags().StringVar(&importFormatFlag, "format", "",
+		"Table format, 'csv' or 'json'.")
+	importCmd.Flags().StringVarP(&importKeyFlag, "key", "k", "",
+		"Key column to match objects by.")
+	importCmd.Flags().BoolVar(&importFileFlag, "file", false,
+		"Create missing objects as files.")
+	importCmd.Flags().BoolVarP(&dryRunFlag, "dry-run", "n", false,
+		"Only print the changes.")
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+}
+
+// Export() or `teflon export` writes metadata tables.
+func Export(cmd *cobra.Command, args []string) {
+	t, err := teflon.ExportTable(findObjects(args[0]), tableKeysFlag)
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't export:", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if tableOutputFlag != "" {
+		f, err := os.Create(tableOutputFlag)
+		if err != nil {
+			log.Fatalln("ABORT: Couldn't create output file:", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch exportFormatFlag {
+	case "csv":
+		err = t.WriteCSV(w)
+	case "json":
+		err = t.WriteJSON(w)
+	default:
+		log.Fatalln("ABORT: Unknown format:", exportFormatFlag)
+	}
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't write table:", err)
+	}
+	log.Printf("SUCCESS: Exported %d objects.", len(t.Rows))
+}
+
+// Import() or `teflon import` sets metadata from tables.
+func Import(cmd *cobra.Command, args []string) {
+	f, err := os.Open(args[0])
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't open table:", err)
+	}
+	defer f.Close()
+
+	format := importFormatFlag
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(args[0])), ".")
+	}
+	var t *teflon.Table
+	switch format {
+	case "json":
+		t, err = teflon.ReadJSON(f)
+	default:
+		t, err = teflon.ReadCSV(f)
+	}
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't read table:", err)
+	}
+
+	// Create object for current working directory
+	pwd, err := teflon.NewTeflonObject(".")
+	if err != nil {
+		log.Fatalln("Couldn't create object for '.' :", err)
+	}
+
+	plans, err := pwd.PlanImport(t, importKeyFlag)
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't match table:", err)
+	}
+	for _, p := range plans {
+		fmt.Println(p)
+	}
+	if dryRunFlag {
+		log.Printf("SUCCESS: Dry run, %d objects would change.", len(plans))
+		return
+	}
+
+	if err := teflon.ApplyImport(plans, importFileFlag); err != nil {
+		log.Fatalln("ABORT: Couldn't import:", err)
+	}
+	log.Printf("SUCCESS: Imported %d objects.", len(plans))
+}
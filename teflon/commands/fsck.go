@@ -0,0 +1,83 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gradient-images/teflon"
+
+	"github.com/spf13/cobra"
+)
+
+var fsckRepairFlag bool
+
+var fsckCmd = &cobra.Command{
+	Use:   "fsck [--repair] [<target>]",
+	Short: "Checks the consistency of a show's meta files",
+	Long: `'teflon fsck' walks the file-system under <target> and reports problems of the
+meta files, one per line, starting with a stable problem code:
+
+  F001  meta file of a non-existent object
+  F002  meta file that can't be decoded
+  F003  Instances entry pointing to a non-existent object
+  F004  show root inside another show
+  F005  stale lock file
+  F006  leftover temporary file of an interrupted write
+
+If no <target> is given the show of '.' is checked. With '--repair' orphaned meta
+files, stale lock and temporary files are deleted, dangling Instances entries
+are pruned and corrupt meta files are moved aside with a '.corrupt' extension.
+The command exits with a non-zero status if unrepaired problems remain.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  Fsck,
+}
+
+func init() {
+	fsckCmd.Flags().BoolVar(&fsckRepairFlag, "repair", false,
+		"Repair the problems found where possible.")
+	rootCmd.AddCommand(fsckCmd)
+}
+
+// Fsck() or `teflon fsck` checks and repairs meta files.
+func Fsck(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		args = append(args, "//")
+	}
+
+	fspath, err := teflon.Path(args[0])
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't resolve target:", err)
+	}
+
+	ps, err := teflon.Fsck(fspath, fsckRepairFlag)
+	for _, p := range ps {
+		fmt.Println(p)
+	}
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't finish checking:", err)
+	}
+
+	remaining := 0
+	for _, p := range ps {
+		if !p.Repaired {
+			remaining++
+		}
+	}
+	if remaining > 0 {
+		log.Fatalf("ABORT: %d problems remain.", remaining)
+	}
+	log.Printf("SUCCESS: Checked %s, %d problems repaired.", fspath, len(ps))
+}
@@ -23,16 +23,26 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var getOriginFlag bool
+
 var getCmd = &cobra.Command{
 	Use:   "get [<expr>]",
 	Short: "Reads Teflon metadata",
 	Long: `'teflon get' prints the metadata belonging to the targets.  If no <expr>
-is specified it will return all the metadata for '.'.`,
+is specified it will return all the metadata for '.'. With the '-o' flag <expr>
+has to be an object selector, and the user metadata of the matching objects is
+printed together with the path of the object each value was inherited from.`,
 	Args: cobra.ExactArgs(1),
 	Run:  Get,
 }
 
 func init() {
+	getCmd.Flags().BoolVarP(
+		&getOriginFlag,
+		"origin",
+		"o",
+		false,
+		"Print the origin of the user metadata values.")
 	rootCmd.AddCommand(getCmd)
 }
 
@@ -44,10 +54,24 @@ func Get(cmd *cobra.Command, args []string) {
 		log.Fatalln("Couldn't create object for '.' :", err)
 	}
 
-	// Run Get.
-	res, err := pwd.Get(args[0])
-	if err != nil {
-		log.Fatalln("ABORT: Couldn't get results:", err)
+	var res interface{}
+	if getOriginFlag {
+		// Collect inherited metadata of the matching objects.
+		objs, err := pwd.Find(args[0])
+		if err != nil {
+			log.Fatalln("ABORT: Couldn't find objects:", err)
+		}
+		orm := map[string]map[string]teflon.MetaOrigin{}
+		for _, o := range objs {
+			orm[o.Path] = o.InheritedMeta()
+		}
+		res = orm
+	} else {
+		// Run Get.
+		res, err = pwd.Get(args[0])
+		if err != nil {
+			log.Fatalln("ABORT: Couldn't get results:", err)
+		}
 	}
 
 	close(teflon.Events)
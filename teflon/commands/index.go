@@ -0,0 +1,92 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Maintains the index files of shows",
+	Long: `'teflon index' groups the commands maintaining the index files of shows.
+
+The index of a show caches the children of its directories, and the file info
+and metadata of the children, so queries don't have to read every file and meta
+file of the show. It is stored in '.teflon/cache/index' in the show root. When a
+show has its index turned on, it is used by all queries transparently, and kept
+up to date by teflon's own writes.
+
+A directory is read from the disk again when its modification time or the
+modification time of its Teflon directory changes, which happens when files are
+added, removed or renamed, or meta files are written. Files changed in place,
+and metadata written in extended attributes by other programs, are not noticed
+until the index is rebuilt.`,
+	Run: RootRun,
+}
+
+var indexBuildCmd = &cobra.Command{
+	Use:   "build [<expr>]",
+	Short: "Turns on and rebuilds the index of shows",
+	Long: `'teflon index build' turns on the index of the shows selected by <expr> and
+builds it from scratch by reading the whole show. If no <expr> is given the show
+of '.' is indexed. Only shows keeping their metadata in sidecar files can be
+indexed, since writing extended attributes leaves modification times alone.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  IndexBuild,
+}
+
+var indexDropCmd = &cobra.Command{
+	Use:   "drop [<expr>]",
+	Short: "Turns off the index of shows",
+	Long: `'teflon index drop' turns off the index of the shows selected by <expr> and
+removes the index file. If no <expr> is given the show of '.' is changed.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  IndexDrop,
+}
+
+func init() {
+	indexCmd.AddCommand(indexBuildCmd)
+	indexCmd.AddCommand(indexDropCmd)
+	rootCmd.AddCommand(indexCmd)
+}
+
+// IndexBuild() or `teflon index build` builds show indexes.
+func IndexBuild(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		args = append(args, "//")
+	}
+	for _, r := range findObjects(args[0]) {
+		count, err := r.BuildIndex()
+		if err != nil {
+			log.Fatalln("ABORT: Couldn't build index:", err)
+		}
+		log.Printf("SUCCESS: Indexed %d directories of: %s", count, r.Path)
+	}
+}
+
+// IndexDrop() or `teflon index drop` removes show indexes.
+func IndexDrop(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		args = append(args, "//")
+	}
+	for _, r := range findObjects(args[0]) {
+		if err := r.DropIndex(); err != nil {
+			log.Fatalln("ABORT: Couldn't drop index:", err)
+		}
+		log.Println("SUCCESS: Dropped index of:", r.Path)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gradient-images/teflon"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	logKeyFlag   []string
+	logUserFlag  string
+	logSinceFlag string
+	logUntilFlag string
+)
+
+var logCmd = &cobra.Command{
+	Use:   "log [<expr>]",
+	Short: "Prints the metadata history of objects",
+	Long: `'teflon log' prints the metadata changes recorded in the show's journal for
+the objects selected by <expr>. If no <expr> is given it prints the history of
+'.'. Times for '--since' and '--until' can be given as '2006-01-02',
+'2006-01-02 15:04' or in RFC 3339 format, and are local time unless a zone is
+given.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  Log,
+}
+
+func init() {
+	logCmd.Flags().StringSliceVarP(&logKeyFlag, "key", "k", []string{},
+		"Comma separated list of keys to show changes of.")
+	logCmd.Flags().StringVarP(&logUserFlag, "user", "u", "",
+		"Show only the changes made by this user.")
+	logCmd.Flags().StringVar(&logSinceFlag, "since", "",
+		"Show only the changes made after this time.")
+	logCmd.Flags().StringVar(&logUntilFlag, "until", "",
+		"Show only the changes made before this time.")
+	rootCmd.AddCommand(logCmd)
+}
+
+// Log() or `teflon log` prints the metadata history of objects.
+func Log(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		args = append(args, ".")
+	}
+
+	f := &teflon.JournalFilter{Keys: logKeyFlag, User: logUserFlag}
+	var err error
+	if f.Since, err = parseTime(logSinceFlag); err != nil {
+		log.Fatalln("ABORT: Bad '--since' time:", err)
+	}
+	if f.Until, err = parseTime(logUntilFlag); err != nil {
+		log.Fatalln("ABORT: Bad '--until' time:", err)
+	}
+
+	// Create object for current working directory
+	pwd, err := teflon.NewTeflonObject(".")
+	if err != nil {
+		log.Fatalln("Couldn't create object for '.' :", err)
+	}
+
+	objs, err := pwd.Find(args[0])
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't find objects:", err)
+	}
+
+	for _, o := range objs {
+		es, err := o.History(f)
+		if err != nil {
+			log.Fatalln("ABORT: Couldn't read journal:", err)
+		}
+		for _, e := range es {
+			fmt.Printf("%s %s@%s %s\n", e.Time.Local().Format("2006-01-02 15:04:05"), e.User, e.Host, e.Path)
+			for _, ch := range e.Changes {
+				fmt.Printf("    %s: %s -> %s\n", ch.Key, logValue(ch.Old), logValue(ch.New))
+			}
+		}
+	}
+}
+
+// Parses the time formats accepted by the log command. Empty string results
+// in zero time.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("Unknown time format: " + s)
+}
+
+// Display string of a journaled value.
+func logValue(v *string) string {
+	if v == nil {
+		return "<none>"
+	}
+	return fmt.Sprintf("%q", *v)
+}
@@ -0,0 +1,216 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"log"
+
+	"github.com/gradient-images/teflon"
+
+	"github.com/spf13/cobra"
+)
+
+var metaFormatFlag string
+
+var metaCmd = &cobra.Command{
+	Use:   "meta",
+	Short: "Maintains meta files",
+	Long:  `'teflon meta' groups the commands maintaining the meta files of shows.`,
+	Run:   RootRun,
+}
+
+var metaConvertCmd = &cobra.Command{
+	Use:   "convert -t <format> [<expr>]",
+	Short: "Converts meta files to another format",
+	Long: `'teflon meta convert' rewrites the meta files of the objects selected by <expr>
+and all of their descendants in the format given by '-t'. The format can be
+'protobuf', 'json' or 'yaml'. If no <expr> is given the whole show of '.' is
+converted. When a show root is converted, the show's config is also updated, so
+later writes use the new format too.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  MetaConvert,
+}
+
+var metaMigrateCmd = &cobra.Command{
+	Use:   "migrate [<expr>]",
+	Short: "Upgrades meta files to the current format version",
+	Long: `'teflon meta migrate' rewrites the meta files of the objects selected by <expr>
+and all of their descendants that were written in an older format version. If no
+<expr> is given the whole show of '.' is migrated. Old meta files are upgraded in
+memory whenever they are read, so migrating is only needed to make the upgrade
+permanent.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  MetaMigrate,
+}
+
+var metaStoreCmd = &cobra.Command{
+	Use:   "store <store> [<expr>]",
+	Short: "Moves the metadata of shows to another store",
+	Long: `'teflon meta store' moves the metadata of the shows selected by <expr> to
+another store, and sets it in the show config so later writes use it too. The
+store can be:
+
+  sidecar  meta files in '.teflon' directories next to the objects (default)
+  xattr    the 'user.teflon.*' extended attributes of the objects (Linux only)
+  memory   kept in memory for the lifetime of the process, for tests
+
+Show roots always keep their metadata in sidecar files, next to the show config.
+If no <expr> is given the show of '.' is moved.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run:  MetaStore,
+}
+
+func init() {
+	metaConvertCmd.Flags().StringVarP(&metaFormatFlag, "to", "t", "",
+		"Format to convert to.")
+	metaCmd.AddCommand(metaConvertCmd)
+	metaCmd.AddCommand(metaMigrateCmd)
+	metaCmd.AddCommand(metaStoreCmd)
+	rootCmd.AddCommand(metaCmd)
+}
+
+// MetaConvert() or `teflon meta convert` converts meta files between formats.
+func MetaConvert(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		args = append(args, "//")
+	}
+	switch metaFormatFlag {
+	case teflon.FormatProtobuf, teflon.FormatJSON, teflon.FormatYAML:
+	default:
+		log.Fatalln("ABORT: Unknown format:", metaFormatFlag)
+	}
+
+	// Create object for current working directory
+	pwd, err := teflon.NewTeflonObject(".")
+	if err != nil {
+		log.Fatalln("Couldn't create object for '.' :", err)
+	}
+
+	roots, err := pwd.Find(args[0])
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't find objects:", err)
+	}
+
+	for _, r := range roots {
+		if r.ShowRoot {
+			c, err := r.Config()
+			if err != nil {
+				log.Fatalln("ABORT: Couldn't read show config:", err)
+			}
+			c.MetaFormat = metaFormatFlag
+			if err := r.SaveConfig(c); err != nil {
+				log.Fatalln("ABORT: Couldn't write show config:", err)
+			}
+		}
+
+		count := 0
+		convert := func(o *teflon.TeflonObject) error {
+			if !o.HasMeta() {
+				return nil
+			}
+			count++
+			return o.ConvertMeta(metaFormatFlag)
+		}
+		// Frames of sequences are not walked, but they have their own metadata.
+		err := r.Walk(func(o *teflon.TeflonObject) error {
+			if err := convert(o); err != nil {
+				return err
+			}
+			for _, n := range o.Frames() {
+				f, err := teflon.NewTeflonObject(o.FramePath(n))
+				if err != nil {
+					return err
+				}
+				if err := convert(f); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatalln("ABORT: Couldn't convert meta files:", err)
+		}
+		log.Printf("SUCCESS: Converted %d meta files under: %s", count, r.Path)
+	}
+}
+
+// MetaMigrate() or `teflon meta migrate` upgrades meta files in place.
+func MetaMigrate(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		args = append(args, "//")
+	}
+
+	// Create object for current working directory
+	pwd, err := teflon.NewTeflonObject(".")
+	if err != nil {
+		log.Fatalln("Couldn't create object for '.' :", err)
+	}
+
+	roots, err := pwd.Find(args[0])
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't find objects:", err)
+	}
+
+	for _, r := range roots {
+		count := 0
+		migrate := func(o *teflon.TeflonObject) error {
+			ok, err := o.MigrateMeta()
+			if ok {
+				count++
+			}
+			return err
+		}
+		// Frames of sequences are not walked, but they have their own metadata.
+		err := r.Walk(func(o *teflon.TeflonObject) error {
+			if err := migrate(o); err != nil {
+				return err
+			}
+			for _, n := range o.Frames() {
+				f, err := teflon.NewTeflonObject(o.FramePath(n))
+				if err != nil {
+					return err
+				}
+				if err := migrate(f); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatalln("ABORT: Couldn't migrate meta files:", err)
+		}
+		log.Printf("SUCCESS: Migrated %d meta files to version %d under: %s", count, teflon.MetaVersion(), r.Path)
+	}
+}
+
+// MetaStore() or `teflon meta store` moves metadata between stores.
+func MetaStore(cmd *cobra.Command, args []string) {
+	if len(args) == 1 {
+		args = append(args, "//")
+	}
+	if args[0] == teflon.StoreMemory {
+		log.Fatalln("ABORT: The memory store doesn't outlive the process.")
+	}
+
+	for _, r := range findObjects(args[1]) {
+		if !r.ShowRoot {
+			log.Fatalln("ABORT: Not a show root:", r.Path)
+		}
+		count, err := r.ChangeMetaStore(args[0])
+		if err != nil {
+			log.Fatalln("ABORT: Couldn't move metadata:", err)
+		}
+		log.Printf("SUCCESS: Moved metadata of %d objects to the %s store under: %s", count, args[0], r.Path)
+	}
+}
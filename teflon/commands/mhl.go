@@ -0,0 +1,128 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gradient-images/teflon"
+
+	"github.com/spf13/cobra"
+)
+
+var mhlOutputFlag string
+
+var mhlCmd = &cobra.Command{
+	Use:   "mhl",
+	Short: "Creates and verifies ASC Media Hash Lists",
+	Long: `'teflon mhl' groups the commands working with ASC Media Hash List (MHL)
+manifests of deliveries.`,
+	Run: RootRun,
+}
+
+var mhlCreateCmd = &cobra.Command{
+	Use:   "create [-o <file>] <expr>",
+	Short: "Writes an MHL manifest for a directory tree",
+	Long: `'teflon mhl create' writes an MHL manifest of all the files under the
+directories selected by <expr>. The checksums are taken from the metadata of the
+files, files that weren't hashed since they last changed are hashed first. The
+manifest is written into the directory as '<dirname>_<date>_<time>.mhl', unless
+an output file is given with '-o'. The output file has to be in the directory or
+in one of its ancestors, file paths in the manifest are relative to it.`,
+	Args: cobra.ExactArgs(1),
+	Run:  MHLCreate,
+}
+
+var mhlVerifyCmd = &cobra.Command{
+	Use:   "verify <manifest>",
+	Short: "Verifies a delivery against its MHL manifest",
+	Long: `'teflon mhl verify' checks the files under the directory of <manifest>
+against it, and records the result in the Verification section of each listed
+file's metadata. Problems are reported like with 'teflon verify'. The command
+exits with a non-zero status if there are mismatching or missing files.`,
+	Args: cobra.ExactArgs(1),
+	Run:  MHLVerify,
+}
+
+func init() {
+	mhlCreateCmd.Flags().StringVarP(&mhlOutputFlag, "output", "o", "",
+		"File to write the manifest to.")
+	mhlCmd.AddCommand(mhlCreateCmd)
+	mhlCmd.AddCommand(mhlVerifyCmd)
+	rootCmd.AddCommand(mhlCmd)
+}
+
+// MHLCreate() or `teflon mhl create` writes MHL manifests.
+func MHLCreate(cmd *cobra.Command, args []string) {
+	objs := findObjects(args[0])
+	if mhlOutputFlag != "" && len(objs) > 1 {
+		log.Fatalln("ABORT: '-o' can only be used with a single directory.")
+	}
+
+	for _, o := range objs {
+		out := mhlOutputFlag
+		if out == "" {
+			name := o.FileInfo.Name + time.Now().Format("_2006-01-02_150405") + teflon.MHLExtension
+			out = filepath.Join(o.Path, name)
+		}
+		out, err := filepath.Abs(out)
+		if err != nil {
+			log.Fatalln("ABORT: Couldn't resolve output file:", err)
+		}
+
+		m, err := o.CreateMHL(filepath.Dir(out))
+		if err != nil {
+			log.Fatalln("ABORT: Couldn't create manifest:", err)
+		}
+		if err := m.Write(out); err != nil {
+			log.Fatalln("ABORT: Couldn't write manifest:", err)
+		}
+		log.Printf("SUCCESS: Wrote manifest of %d files: %s", len(m.Hashes), out)
+	}
+}
+
+// MHLVerify() or `teflon mhl verify` verifies a delivery.
+func MHLVerify(cmd *cobra.Command, args []string) {
+	fspath, err := teflon.Path(args[0])
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't resolve manifest:", err)
+	}
+	if _, err := os.Stat(fspath); err != nil {
+		log.Fatalln("ABORT: Couldn't find manifest:", err)
+	}
+
+	rs, err := teflon.VerifyMHL(fspath)
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't verify:", err)
+	}
+
+	count := map[string]int{}
+	for _, r := range rs {
+		count[r.Status]++
+		if r.Status != teflon.VerifyOK {
+			fmt.Println(r)
+		}
+	}
+
+	msg := fmt.Sprintf("%d ok, %d mismatch, %d missing, %d new.", count[teflon.VerifyOK],
+		count[teflon.VerifyMismatch], count[teflon.VerifyMissing], count[teflon.VerifyNew])
+	if count[teflon.VerifyMismatch] > 0 || count[teflon.VerifyMissing] > 0 {
+		log.Fatalln("ABORT: Verification failed:", msg)
+	}
+	log.Println("SUCCESS: Verified:", msg)
+}
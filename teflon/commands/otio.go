@@ -0,0 +1,85 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"io"
+	"log"
+	"os"
+
+	"github.com/gradient-images/teflon"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	otioNameFlag   string
+	otioOutputFlag string
+)
+
+var exportOTIOCmd = &cobra.Command{
+	Use:   "export-otio [--name <name>] [-o <file>] <expr>",
+	Short: "Writes an OpenTimelineIO timeline of shots",
+	Long: `'teflon export-otio' writes the shots selected by <expr> as an OpenTimelineIO
+(.otio) timeline, so editorial can load the current state of a sequence. If the
+selected objects aren't shots, their child shots are used. Shots are the objects
+with cut metadata, like the ones created by 'teflon import-edl'.
+
+The clips of the timeline are ordered by the 'order' key, and span 'duration'
+frames from 'cutIn' at 'fps'. If all the shots have a 'recIn' record timecode,
+the spaces between them become gaps. The metadata of each shot is stored in the
+'teflon' metadata of its clip.
+
+The timeline is named after the first selected object unless '--name' is given,
+and written to the standard output unless '-o' is given.`,
+	Args: cobra.ExactArgs(1),
+	Run:  ExportOTIO,
+}
+
+func init() {
+	exportOTIOCmd.Flags().StringVar(&otioNameFlag, "name", "",
+		"Name of the timeline.")
+	exportOTIOCmd.Flags().StringVarP(&otioOutputFlag, "output", "o", "",
+		"File to write the timeline to.")
+	rootCmd.AddCommand(exportOTIOCmd)
+}
+
+// ExportOTIO() or `teflon export-otio` writes OTIO timelines.
+func ExportOTIO(cmd *cobra.Command, args []string) {
+	objs := findObjects(args[0])
+	shots := teflon.FindShots(objs)
+
+	name := otioNameFlag
+	if name == "" && len(objs) > 0 {
+		name = objs[0].FileInfo.Name
+	}
+	t, err := teflon.CutTimeline(name, shots)
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't build timeline:", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if otioOutputFlag != "" {
+		f, err := os.Create(otioOutputFlag)
+		if err != nil {
+			log.Fatalln("ABORT: Couldn't create output file:", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := t.Write(w); err != nil {
+		log.Fatalln("ABORT: Couldn't write timeline:", err)
+	}
+	log.Printf("SUCCESS: Exported %d shots.", len(shots))
+}
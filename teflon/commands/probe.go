@@ -0,0 +1,89 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gradient-images/teflon"
+
+	"github.com/spf13/cobra"
+)
+
+var probeRecursiveFlag bool
+
+var probeCmd = &cobra.Command{
+	Use:   "probe [-r] <expr>",
+	Short: "Reads image headers into metadata",
+	Long: `'teflon probe' reads the headers of the image files selected by <expr> and
+stores the resolution, channels, bit depth, pixel aspect and, for OpenEXR files,
+the data and display windows in the ImgInfo section of their metadata. These are
+available in expressions afterwards, like 'ImgInfo.Width@'. Supported formats are
+PNG, JPEG, TIFF, DPX and OpenEXR, other files are skipped. With '-r' the
+descendants of the selected objects are probed too.`,
+	Args: cobra.ExactArgs(1),
+	Run:  Probe,
+}
+
+func init() {
+	probeCmd.Flags().BoolVarP(&probeRecursiveFlag, "recursive", "r", false,
+		"Probe the descendants of the selected objects too.")
+	rootCmd.AddCommand(probeCmd)
+}
+
+// Probe() or `teflon probe` fills ImgInfo from image headers.
+func Probe(cmd *cobra.Command, args []string) {
+	// Create object for current working directory
+	pwd, err := teflon.NewTeflonObject(".")
+	if err != nil {
+		log.Fatalln("Couldn't create object for '.' :", err)
+	}
+
+	objs, err := pwd.Find(args[0])
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't find objects:", err)
+	}
+
+	count := 0
+	probe := func(o *teflon.TeflonObject) error {
+		if o.FileInfo.IsDir {
+			return nil
+		}
+		err := o.Probe()
+		if err == teflon.ErrUnknownImage {
+			log.Println("DEBUG: Not an image:", o.Path)
+			return nil
+		}
+		if err != nil {
+			log.Printf("WARNING: Couldn't probe %s: %v", o.Path, err)
+			return nil
+		}
+		fmt.Printf("%s: %s %dx%d\n", o.Path, o.ImgInfo.Format, o.ImgInfo.Width, o.ImgInfo.Height)
+		count++
+		return nil
+	}
+
+	for _, o := range objs {
+		if probeRecursiveFlag {
+			err = o.Walk(probe)
+		} else {
+			err = probe(o)
+		}
+		if err != nil {
+			log.Fatalln("ABORT: Couldn't probe:", err)
+		}
+	}
+	log.Printf("SUCCESS: Probed %d images.", count)
+}
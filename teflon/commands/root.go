@@ -17,6 +17,8 @@ package commands
 import (
 	"log"
 
+	"github.com/gradient-images/teflon"
+
 	"github.com/spf13/cobra"
 )
 
@@ -31,6 +33,11 @@ pipeline creation.`,
 	Run: RootRun,
 }
 
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&teflon.WriteProtected, "protected", false,
+		"Allow writing keys in protected namespaces.")
+}
+
 // Without arguments the `teflon` command prints the help message.
 func RootRun(cmd *cobra.Command, args []string) {
 	cmd.Help()
@@ -39,7 +46,13 @@ func RootRun(cmd *cobra.Command, args []string) {
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	if teflon.TeflonConf == "" {
+		log.Fatalln("FATAL: TEFLONCONF environment variable is not set.")
+	}
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatalln(err)
 	}
+	if err := teflon.SaveIndexes(); err != nil {
+		log.Println("WARNING: Couldn't save index:", err)
+	}
 }
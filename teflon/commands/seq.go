@@ -0,0 +1,222 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/gradient-images/teflon"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	seqOffsetFlag  int32
+	seqPaddingFlag int32
+)
+
+var seqCmd = &cobra.Command{
+	Use:   "seq",
+	Short: "Works with frame sequences",
+	Long: `'teflon seq' groups the commands acting on whole frame sequences. Sequences are
+addressed by their pattern name, like 'plate.%04d.exr'. All commands keep the
+metadata of the sequence and its frames, and update the frame range recorded in
+the sequence's metadata.`,
+	Run: RootRun,
+}
+
+var seqListCmd = &cobra.Command{
+	Use:   "list [<expr>]",
+	Short: "Lists sequences with their frame ranges",
+	Long: `'teflon seq list' lists the objects selected by <expr>, or the contents of them
+if they are directories. Sequences are listed on one line with their frame
+range, number of frames and missing frames, like:
+
+  plate.[1001-1020].exr 13 frames, missing 1011-1012 1015-1019
+
+If no <expr> is given the contents of '.' are listed.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  SeqList,
+}
+
+var seqRenumberCmd = &cobra.Command{
+	Use:   "renumber -o <offset> <expr>",
+	Short: "Shifts the frame numbers of sequences",
+	Long: `'teflon seq renumber' adds <offset> to the frame numbers of the sequences
+selected by <expr>. The offset can be negative.`,
+	Args: cobra.ExactArgs(1),
+	Run:  SeqRenumber,
+}
+
+var seqPadCmd = &cobra.Command{
+	Use:   "pad -p <padding> <expr>",
+	Short: "Changes the padding of sequences",
+	Long: `'teflon seq pad' renames the frames of the sequences selected by <expr> so
+their frame numbers are zero padded to <padding> digits. A padding of 1 means no
+padding.`,
+	Args: cobra.ExactArgs(1),
+	Run:  SeqPad,
+}
+
+var seqRenameCmd = &cobra.Command{
+	Use:   "rename <expr> <base>",
+	Short: "Changes the base name of sequences",
+	Long: `'teflon seq rename' renames the frames of the sequences selected by <expr> to
+<base>, keeping their frame numbers, padding and extension.`,
+	Args: cobra.ExactArgs(2),
+	Run:  SeqRename,
+}
+
+var seqCopyCmd = &cobra.Command{
+	Use:   "copy <expr> <target>",
+	Short: "Copies sequences",
+	Long: `'teflon seq copy' copies the sequences selected by <expr> to <target>, which
+is either a directory or a sequence name, like 'dir/comp.%05d.exr'.`,
+	Args: cobra.ExactArgs(2),
+	Run:  SeqCopy,
+}
+
+var seqMoveCmd = &cobra.Command{
+	Use:   "move <expr> <target>",
+	Short: "Moves sequences",
+	Long: `'teflon seq move' moves the sequences selected by <expr> to <target>, which
+is either a directory or a sequence name, like 'dir/comp.%05d.exr'.`,
+	Args: cobra.ExactArgs(2),
+	Run:  SeqMove,
+}
+
+func init() {
+	seqRenumberCmd.Flags().Int32VarP(&seqOffsetFlag, "offset", "o", 0,
+		"Number to add to the frame numbers.")
+	seqPadCmd.Flags().Int32VarP(&seqPaddingFlag, "padding", "p", 4,
+		"Number of digits of the frame numbers.")
+	seqCmd.AddCommand(seqListCmd)
+	seqCmd.AddCommand(seqRenumberCmd)
+	seqCmd.AddCommand(seqPadCmd)
+	seqCmd.AddCommand(seqRenameCmd)
+	seqCmd.AddCommand(seqCopyCmd)
+	seqCmd.AddCommand(seqMoveCmd)
+	rootCmd.AddCommand(seqCmd)
+}
+
+// SeqList() or `teflon seq list` lists sequences like lsseq.
+func SeqList(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		args = append(args, ".")
+	}
+
+	for _, o := range findObjects(args[0]) {
+		if !o.FileInfo.IsDir {
+			fmt.Println(seqLine(o))
+			continue
+		}
+		chs := o.Children()
+		sort.Slice(chs, func(i, j int) bool { return chs[i].Path < chs[j].Path })
+		for _, ch := range chs {
+			if ch.FileInfo.Name != ".teflon" {
+				fmt.Println(seqLine(ch))
+			}
+		}
+	}
+}
+
+// Formats an object for listing.
+func seqLine(o *teflon.TeflonObject) string {
+	if !o.IsSeq() {
+		return o.FileInfo.Name
+	}
+	s := o.Seq
+	l := fmt.Sprintf("%s.[%0*d-%0*d].%s %d frames", s.BaseName, s.Padding, s.First,
+		s.Padding, s.Last, s.Extension, len(o.Frames()))
+	gaps := []string{}
+	for _, g := range o.Gaps() {
+		if g[0] == g[1] {
+			gaps = append(gaps, fmt.Sprint(g[0]))
+		} else {
+			gaps = append(gaps, fmt.Sprintf("%d-%d", g[0], g[1]))
+		}
+	}
+	if len(gaps) > 0 {
+		l += ", missing " + strings.Join(gaps, " ")
+	}
+	return l
+}
+
+// SeqRenumber() or `teflon seq renumber` shifts frame numbers.
+func SeqRenumber(cmd *cobra.Command, args []string) {
+	seqEach(args[0], "Renumbered", func(o *teflon.TeflonObject) (*teflon.TeflonObject, error) {
+		return o.SeqRenumber(seqOffsetFlag)
+	})
+}
+
+// SeqPad() or `teflon seq pad` changes the padding of frame numbers.
+func SeqPad(cmd *cobra.Command, args []string) {
+	seqEach(args[0], "Padded", func(o *teflon.TeflonObject) (*teflon.TeflonObject, error) {
+		return o.SeqPad(seqPaddingFlag)
+	})
+}
+
+// SeqRename() or `teflon seq rename` changes the base name of sequences.
+func SeqRename(cmd *cobra.Command, args []string) {
+	seqEach(args[0], "Renamed", func(o *teflon.TeflonObject) (*teflon.TeflonObject, error) {
+		return o.SeqRename(args[1])
+	})
+}
+
+// SeqCopy() or `teflon seq copy` copies sequences.
+func SeqCopy(cmd *cobra.Command, args []string) {
+	seqEach(args[0], "Copied", func(o *teflon.TeflonObject) (*teflon.TeflonObject, error) {
+		return o.SeqCopy(args[1])
+	})
+}
+
+// SeqMove() or `teflon seq move` moves sequences.
+func SeqMove(cmd *cobra.Command, args []string) {
+	seqEach(args[0], "Moved", func(o *teflon.TeflonObject) (*teflon.TeflonObject, error) {
+		return o.SeqMove(args[1])
+	})
+}
+
+// Runs a sequence operation on the sequences selected by an expression.
+func seqEach(exs, done string, fn func(*teflon.TeflonObject) (*teflon.TeflonObject, error)) {
+	for _, o := range findObjects(exs) {
+		if !o.IsSeq() {
+			log.Println("WARNING: Not a sequence:", o.Path)
+			continue
+		}
+		n, err := fn(o)
+		if err != nil {
+			log.Fatalf("ABORT: Couldn't process %s: %v", o.Path, err)
+		}
+		log.Printf("SUCCESS: %s: %s -> %s", done, o.Path, n.Path)
+	}
+}
+
+// Finds the objects selected by an expression relative to '.'.
+func findObjects(exs string) []*teflon.TeflonObject {
+	// Create object for current working directory
+	pwd, err := teflon.NewTeflonObject(".")
+	if err != nil {
+		log.Fatalln("Couldn't create object for '.' :", err)
+	}
+
+	objs, err := pwd.Find(exs)
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't find objects:", err)
+	}
+	return objs
+}
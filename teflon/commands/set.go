@@ -14,6 +14,8 @@
 package commands
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"strings"
 
@@ -22,49 +24,145 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	setUnsetFlag     []string
+	setNoInheritFlag []string
+	setInheritFlag   []string
+	setEvalFlag      []string
+)
+
 var setCmd = &cobra.Command{
-	Use:   "set <-d key:value..> <target..>",
-	Short: "Sets a user metadata entry on the given target",
-	Long: `Command 'teflon meta set' sets a metadata entry on the the target. If no <target>
-is specified it will run for '.'. If the meta file doesn't exist 'meta set'
-will create a new one. If only a key is given to the -d flag, the entry for
-the key will be deleted.`,
+	Use:   "set [-n] <-m key:value..> [--eval key=expr..] [<expr>..]",
+	Short: "Sets user metadata entries on the selected objects",
+	Long: `Command 'teflon set' sets metadata entries on all the objects selected by the
+expressions, like 'sq010/*' or '//sq*/sh010'. If no <expr> is
+specified it will run for '.'. If the meta file doesn't exist 'set' will create
+a new one. If only a key is given to the -m flag, the entry for the key will be
+deleted, and the target inherits it from its ancestors again. Keys given to
+'-u' are explicitly unset, so they are not inherited either. The '--no-inherit'
+and '--inherit' flags control whether the target's value of a key is passed
+down to its descendants.
+
+Values given to '--eval' are meta selector expressions evaluated for each object
+separately, like '--eval frames=cutOut-cutIn+1'. They are evaluated after the
+'-m' entries are set, in the order they are given.
+
+Derived fields defined in the show config can't be set. Keys can be put into
+namespaces with dots, like 'pipeline.publishId'. The show config can restrict
+the namespaces 'set' can write, and protect namespaces that only authorised
+tools should write. Protected namespaces are written only with '--protected'.
+
+The changes of each object are reported. Objects that wouldn't change are not
+written. With '-n' nothing is written.`,
 	Run: Set,
 }
 
 func init() {
 	setCmd.Flags().StringSliceVarP(&metaListFlag, "meta", "m", []string{},
 		"Metadata entry in the form of 'key:value' pairs")
+	setCmd.Flags().StringArrayVar(&setEvalFlag, "eval", []string{},
+		"Computed metadata entry in the form of 'key=expression'.")
+	setCmd.Flags().StringSliceVarP(&setUnsetFlag, "unset", "u", []string{},
+		"Comma separated list of keys to unset.")
+	setCmd.Flags().StringSliceVar(&setNoInheritFlag, "no-inherit", []string{},
+		"Comma separated list of keys not to pass down to descendants.")
+	setCmd.Flags().StringSliceVar(&setInheritFlag, "inherit", []string{},
+		"Comma separated list of keys to pass down to descendants again.")
+	setCmd.Flags().BoolVarP(&dryRunFlag, "dry-run", "n", false,
+		"Only report the changes.")
 	rootCmd.AddCommand(setCmd)
 }
 
 // Set() or `teflon set` sets and/or deletes metadata entries into the UserSection of
-// the TeflonObject and writes the changes to disk.
+// the selected TeflonObjects and writes the changes to disk.
 func Set(cmd *cobra.Command, args []string) {
 	log.Print("DEBUG: 'set' command called")
 	if len(args) == 0 {
 		args = append(args, ".")
 		log.Println("DEBUG: No targets given, running for '.' .")
 	}
-	for _, target := range args {
-		o, err := teflon.NewTeflonObject(target)
-		if err != nil {
-			log.Fatalln("ABORT: Couldn't create object:", err)
+	for _, data := range setEvalFlag {
+		if !strings.Contains(data, "=") {
+			log.Fatalln("ABORT: Malformed computed metadata:", data)
 		}
-		for _, data := range metaListFlag {
-			s := strings.SplitN(data, ":", 2)
-			if len(s) < 2 {
-				log.Fatalln("ABORT: Malformed metadata:", data)
+	}
+
+	// Expressions may select the same object more than once.
+	objs := []*teflon.TeflonObject{}
+	seen := map[string]bool{}
+	for _, exs := range args {
+		for _, o := range findObjects(exs) {
+			if !seen[o.Path] {
+				seen[o.Path] = true
+				objs = append(objs, o)
 			}
-			if s[1] == "" {
-				log.Println("SUCCESS: Deleting metadata entry:", s[0])
-				o.DelMeta(s[0])
-			} else {
-				log.Printf("SUCCESS: Set metadata: '%s: %s'", s[0], s[1])
-				o.SetMeta(s[0], s[1])
+		}
+	}
+
+	changed := 0
+	for _, o := range objs {
+		// Changes are applied to freshly read metadata under lock, so concurrent
+		// writers don't lose each other's changes.
+		ds, err := o.ChangeMeta(applySet, dryRunFlag)
+		if err != nil {
+			log.Fatalf("ABORT: Couldn't set metadata of %s: %v", teflon.ShowAbs(o.Path), err)
+		}
+		if len(ds) == 0 {
+			continue
+		}
+		changed++
+		fmt.Println(teflon.ObjectDiff{Path: teflon.ShowAbs(o.Path), Status: "~", Changes: ds})
+	}
+	if dryRunFlag {
+		log.Printf("SUCCESS: Dry run, %d objects would change.", changed)
+		return
+	}
+	log.Printf("SUCCESS: Changed %d of %d objects.", changed, len(objs))
+}
+
+// Applies the flags of 'teflon set' to an object. Only the command line is
+// restricted to the writable namespaces of the show, so they are checked here.
+func applySet(o *teflon.TeflonObject) error {
+	for _, data := range metaListFlag {
+		s := strings.SplitN(data, ":", 2)
+		if len(s) < 2 {
+			return errors.New("Malformed metadata: " + data)
+		}
+		if err := o.CheckWritable(s[0], teflon.WriteProtected); err != nil {
+			return err
+		}
+		if s[1] == "" {
+			o.DelMeta(s[0])
+		} else {
+			o.SetMeta(s[0], s[1])
+		}
+	}
+	for _, data := range setEvalFlag {
+		s := strings.SplitN(data, "=", 2)
+		if err := o.CheckWritable(s[0], teflon.WriteProtected); err != nil {
+			return err
+		}
+		v, err := o.EvalMeta(s[1])
+		if err != nil {
+			return fmt.Errorf("Couldn't evaluate '%s': %v", s[1], err)
+		}
+		o.SetMeta(s[0], v)
+	}
+	for _, keys := range [][]string{setUnsetFlag, setNoInheritFlag, setInheritFlag} {
+		for _, key := range keys {
+			if err := o.CheckWritable(key, teflon.WriteProtected); err != nil {
+				return err
 			}
 		}
-		o.SyncMeta()
-		log.Printf("SUCCESS: All changes written to: '%s'", target)
 	}
+	for _, key := range setUnsetFlag {
+		o.UnsetMeta(key)
+	}
+	for _, key := range setNoInheritFlag {
+		o.SetInherit(key, false)
+	}
+	for _, key := range setInheritFlag {
+		o.SetInherit(key, true)
+	}
+	return nil
 }
@@ -0,0 +1,156 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gradient-images/teflon"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapshotOutputFlag string
+	snapshotIntoFlag   string
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Saves and restores the metadata of subtrees",
+	Long: `'teflon snapshot' groups the commands working with snapshots. A snapshot is
+a single compressed file holding the metadata of an object and all of its
+descendants, including Contract, Instances and ShowRoot, but not the media.`,
+	Run: RootRun,
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create [-o <file>] <expr>",
+	Short: "Takes a snapshot of the metadata of a subtree",
+	Long: `'teflon snapshot create' takes a snapshot of the metadata of the objects
+selected by <expr> and their descendants. The snapshot is written to the current
+directory as '<name>_<date>_<time>.tsnap', unless an output file is given with
+'-o'.`,
+	Args: cobra.ExactArgs(1),
+	Run:  SnapshotCreate,
+}
+
+var snapshotDiffCmd = &cobra.Command{
+	Use:   "diff <a> <b>",
+	Short: "Compares two snapshots",
+	Long: `'teflon snapshot diff' lists the objects whose metadata differs between
+snapshots <a> and <b>. Objects only in <b> are marked with '+', objects only in
+<a> with '-', and changed objects with '~' followed by their changed fields.`,
+	Args: cobra.ExactArgs(2),
+	Run:  SnapshotDiff,
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore [-n] [--into <target>] <snapshot>",
+	Short: "Puts the metadata of a snapshot back",
+	Long: `'teflon snapshot restore' writes the metadata of <snapshot> back onto the
+objects it was taken of, or onto the objects under <target> if '--into' is
+given. Only objects that differ from the snapshot are written, and the changes
+are printed. Objects that don't exist anymore are skipped, objects that had no
+metadata in the snapshot get their metadata cleared. Keys in protected
+namespaces are only restored with '--protected'. With '-n' nothing is written.`,
+	Args: cobra.ExactArgs(1),
+	Run:  SnapshotRestore,
+}
+
+func init() {
+	snapshotCreateCmd.Flags().StringVarP(&snapshotOutputFlag, "output", "o", "",
+		"File to write the snapshot to.")
+	snapshotRestoreCmd.Flags().StringVar(&snapshotIntoFlag, "into", "",
+		"Object to restore the snapshot onto.")
+	snapshotRestoreCmd.Flags().BoolVarP(&dryRunFlag, "dry-run", "n", false,
+		"Only print the changes.")
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+	snapshotCmd.AddCommand(snapshotDiffCmd)
+	snapshotCmd.AddCommand(snapshotRestoreCmd)
+	rootCmd.AddCommand(snapshotCmd)
+}
+
+// SnapshotCreate() or `teflon snapshot create` writes snapshots.
+func SnapshotCreate(cmd *cobra.Command, args []string) {
+	objs := findObjects(args[0])
+	if snapshotOutputFlag != "" && len(objs) > 1 {
+		log.Fatalln("ABORT: '-o' can only be used with a single object.")
+	}
+
+	for _, o := range objs {
+		s, err := o.Snapshot()
+		if err != nil {
+			log.Fatalln("ABORT: Couldn't take snapshot:", err)
+		}
+		out := snapshotOutputFlag
+		if out == "" {
+			out = o.FileInfo.Name + s.Time.Format("_2006-01-02_150405") + teflon.SnapshotExtension
+		}
+		if err := s.Write(out); err != nil {
+			log.Fatalln("ABORT: Couldn't write snapshot:", err)
+		}
+		log.Printf("SUCCESS: Wrote snapshot of %d objects: %s", len(s.Objects), out)
+	}
+}
+
+// SnapshotDiff() or `teflon snapshot diff` compares snapshots.
+func SnapshotDiff(cmd *cobra.Command, args []string) {
+	ss := []*teflon.Snapshot{}
+	for _, a := range args {
+		s, err := teflon.ReadSnapshot(a)
+		if err != nil {
+			log.Fatalln("ABORT: Couldn't read snapshot:", err)
+		}
+		ss = append(ss, s)
+	}
+	ds, err := teflon.DiffSnapshots(ss[0], ss[1])
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't compare snapshots:", err)
+	}
+	for _, d := range ds {
+		fmt.Println(d)
+	}
+	log.Printf("SUCCESS: %d objects differ.", len(ds))
+}
+
+// SnapshotRestore() or `teflon snapshot restore` restores snapshots.
+func SnapshotRestore(cmd *cobra.Command, args []string) {
+	s, err := teflon.ReadSnapshot(args[0])
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't read snapshot:", err)
+	}
+	into := snapshotIntoFlag
+	if into == "" {
+		into = s.Root
+	}
+	o, err := teflon.NewTeflonObject(into)
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't create object:", err)
+	}
+
+	ds, err := s.Restore(o, dryRunFlag)
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't restore snapshot:", err)
+	}
+	for _, d := range ds {
+		fmt.Println(d)
+	}
+	if dryRunFlag {
+		log.Printf("SUCCESS: Dry run, %d objects would change.", len(ds))
+		return
+	}
+	log.Printf("SUCCESS: Restored %d objects.", len(ds))
+}
@@ -0,0 +1,79 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gradient-images/teflon"
+
+	"github.com/spf13/cobra"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate [<expr>]",
+	Short: "Checks metadata against the show's schema",
+	Long: `'teflon validate' checks the metadata of the objects selected by <expr> and
+all of their descendants against the schema of their show, and prints every
+violation found. If no <expr> is given the whole show of '.' is validated. The
+command exits with a non-zero status if there were violations.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  Validate,
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}
+
+// Validate() or `teflon validate` reports schema violations of a subtree.
+func Validate(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		args = append(args, "//")
+	}
+
+	// Create object for current working directory
+	pwd, err := teflon.NewTeflonObject(".")
+	if err != nil {
+		log.Fatalln("Couldn't create object for '.' :", err)
+	}
+
+	roots, err := pwd.Find(args[0])
+	if err != nil {
+		log.Fatalln("ABORT: Couldn't find objects:", err)
+	}
+
+	count := 0
+	for _, r := range roots {
+		err := r.Walk(func(o *teflon.TeflonObject) error {
+			vs, err := o.Validate()
+			if err != nil {
+				return err
+			}
+			for _, v := range vs {
+				fmt.Println(v)
+			}
+			count += len(vs)
+			return nil
+		})
+		if err != nil {
+			log.Fatalln("ABORT: Couldn't validate:", err)
+		}
+	}
+
+	if count > 0 {
+		log.Fatalf("ABORT: Found %d violations.", count)
+	}
+	log.Println("SUCCESS: No violations found.")
+}
@@ -14,7 +14,11 @@
 package teflon
 
 import (
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 // Tells if a path is a dir or not.
@@ -34,3 +38,84 @@ func Exist(fspath string) bool {
 	_, err := os.Stat(fspath)
 	return !os.IsNotExist(err)
 }
+
+// Tells if a string slice contains a string.
+func contains(sl []string, s string) bool {
+	for _, e := range sl {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns a copy of a string slice without the occurrences of a string.
+func remove(sl []string, s string) []string {
+	res := []string{}
+	for _, e := range sl {
+		if e != s {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// Writes a file atomically. The data is written to a temporary file in the same
+// directory first, which then replaces the target by renaming.
+func writeFileAtomic(fspath string, data []byte, perm os.FileMode) error {
+	f, err := ioutil.TempFile(filepath.Dir(fspath), "."+filepath.Base(fspath)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp, fspath)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// Copies a file with its permissions.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fi.Mode())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
+
+// Tells if a path is inside a directory or is the directory itself. Symlinks
+// are not resolved.
+func inDir(dir, fspath string) bool {
+	rel, err := filepath.Rel(dir, fspath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
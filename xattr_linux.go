@@ -0,0 +1,49 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"syscall"
+)
+
+func getXattr(fspath, name string) ([]byte, error) {
+	for {
+		n, err := syscall.Getxattr(fspath, name, nil)
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, n)
+		m, err := syscall.Getxattr(fspath, name, buf)
+		if err == syscall.ERANGE {
+			// The attribute grew in the meantime.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return buf[:m], nil
+	}
+}
+
+func setXattr(fspath, name string, value []byte) error {
+	return syscall.Setxattr(fspath, name, value, 0)
+}
+
+func removeXattr(fspath, name string) error {
+	err := syscall.Removexattr(fspath, name)
+	if err == syscall.ENODATA || err == syscall.ENOENT {
+		return nil
+	}
+	return err
+}
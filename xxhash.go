@@ -0,0 +1,145 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// An implementation of the 64 bit xxHash algorithm with a seed of zero. It is
+// the fast hash used by media hash lists, and it's simple enough not to pull in
+// a dependency for it.
+
+// The primes are variables, so the arithmetic on them wraps around.
+var (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+type xxh64 struct {
+	v1, v2, v3, v4 uint64
+	total          uint64
+	mem            [32]byte
+	n              int
+}
+
+// Returns a new xxHash64 hash.Hash64. Its Sum() is big endian.
+func newXXH64() hash.Hash64 {
+	x := &xxh64{}
+	x.Reset()
+	return x
+}
+
+func (x *xxh64) Reset() {
+	x.v1 = xxPrime1 + xxPrime2
+	x.v2 = xxPrime2
+	x.v3 = 0
+	x.v4 = -xxPrime1
+	x.total = 0
+	x.n = 0
+}
+
+func (x *xxh64) Size() int      { return 8 }
+func (x *xxh64) BlockSize() int { return 32 }
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * xxPrime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * xxPrime1
+}
+
+func xxMergeRound(acc, val uint64) uint64 {
+	acc ^= xxRound(0, val)
+	return acc*xxPrime1 + xxPrime4
+}
+
+// Processes 32 byte blocks.
+func (x *xxh64) blocks(b []byte) {
+	le := binary.LittleEndian
+	for ; len(b) >= 32; b = b[32:] {
+		x.v1 = xxRound(x.v1, le.Uint64(b[0:8]))
+		x.v2 = xxRound(x.v2, le.Uint64(b[8:16]))
+		x.v3 = xxRound(x.v3, le.Uint64(b[16:24]))
+		x.v4 = xxRound(x.v4, le.Uint64(b[24:32]))
+	}
+}
+
+func (x *xxh64) Write(b []byte) (int, error) {
+	l := len(b)
+	x.total += uint64(l)
+
+	if x.n+len(b) < 32 {
+		x.n += copy(x.mem[x.n:], b)
+		return l, nil
+	}
+	if x.n > 0 {
+		c := copy(x.mem[x.n:], b)
+		x.blocks(x.mem[:])
+		b = b[c:]
+		x.n = 0
+	}
+	full := len(b) &^ 31
+	x.blocks(b[:full])
+	x.n = copy(x.mem[:], b[full:])
+	return l, nil
+}
+
+func (x *xxh64) Sum64() uint64 {
+	var h uint64
+	if x.total >= 32 {
+		h = bits.RotateLeft64(x.v1, 1) + bits.RotateLeft64(x.v2, 7) +
+			bits.RotateLeft64(x.v3, 12) + bits.RotateLeft64(x.v4, 18)
+		h = xxMergeRound(h, x.v1)
+		h = xxMergeRound(h, x.v2)
+		h = xxMergeRound(h, x.v3)
+		h = xxMergeRound(h, x.v4)
+	} else {
+		h = xxPrime5
+	}
+	h += x.total
+
+	le := binary.LittleEndian
+	b := x.mem[:x.n]
+	for ; len(b) >= 8; b = b[8:] {
+		h ^= xxRound(0, le.Uint64(b))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+	}
+	if len(b) >= 4 {
+		h ^= uint64(le.Uint32(b)) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		b = b[4:]
+	}
+	for _, c := range b {
+		h ^= uint64(c) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+	return h
+}
+
+func (x *xxh64) Sum(in []byte) []byte {
+	s := x.Sum64()
+	return append(in, byte(s>>56), byte(s>>48), byte(s>>40), byte(s>>32),
+		byte(s>>24), byte(s>>16), byte(s>>8), byte(s))
+}
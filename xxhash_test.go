@@ -0,0 +1,72 @@
+// Copyright © 2019 Máté Birkás <gadfly16@gmail.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teflon
+
+import (
+	"strings"
+	"testing"
+)
+
+// Reference values of xxHash64 with a seed of zero.
+var xxh64Tests = []struct {
+	in  string
+	sum uint64
+}{
+	{"", 0xef46db3751d8e999},
+	{"a", 0xd24ec4f1a98c6e5b},
+	{"as", 0x1c330fb2d66be179},
+	{"asd", 0x631c37ce72a97393},
+	{"asdf", 0x415872f599cea71e},
+	{"Call me Ishmael. Some years ago--never mind how long precisely-", 0x02a2e85470d6fd96},
+}
+
+func TestXXH64(t *testing.T) {
+	for _, tt := range xxh64Tests {
+		h := newXXH64()
+		h.Write([]byte(tt.in))
+		if got := h.Sum64(); got != tt.sum {
+			t.Errorf("xxh64(%q) = %016x, want %016x", tt.in, got, tt.sum)
+		}
+	}
+}
+
+// Writing in pieces has to give the same sum as writing at once, whatever the
+// pieces are.
+func TestXXH64Chunks(t *testing.T) {
+	in := []byte(strings.Repeat("0123456789abcdef", 20) + "tail")
+	h := newXXH64()
+	h.Write(in)
+	want := h.Sum64()
+
+	for _, size := range []int{1, 3, 7, 31, 32, 33, 100} {
+		h.Reset()
+		for i := 0; i < len(in); i += size {
+			end := i + size
+			if end > len(in) {
+				end = len(in)
+			}
+			h.Write(in[i:end])
+		}
+		if got := h.Sum64(); got != want {
+			t.Errorf("Writing %d byte chunks: %016x, want %016x", size, got, want)
+		}
+	}
+
+	b := h.Sum(nil)
+	for i := 0; i < 8; i++ {
+		if b[i] != byte(want>>(56-8*uint(i))) {
+			t.Fatalf("Sum() is not big endian: %x", b)
+		}
+	}
+}